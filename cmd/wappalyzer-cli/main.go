@@ -1,29 +1,41 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"encoding/xml"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	wappalyzer "github.com/projectdiscovery/wappalyzergo"
 )
 
 var (
-	url        = flag.String("url", "", "URL to analyze")
-	output     = flag.String("output", "json", "Output format: json, table, csv")
-	timeout    = flag.Duration("timeout", 10*time.Second, "HTTP timeout")
-	userAgent  = flag.String("user-agent", "wappalyzer-cli/1.0", "User agent string")
-	verbose    = flag.Bool("verbose", false, "Verbose output")
-	categories = flag.Bool("categories", false, "Include category information")
-	info       = flag.Bool("info", false, "Include detailed app information")
+	url         = flag.String("url", "", "URL to analyze")
+	input       = flag.String("input", "", "File of URLs to analyze, one per line (- for stdin)")
+	concurrency = flag.Int("concurrency", 5, "Number of URLs analyzed in parallel with -input")
+	output      = flag.String("output", "json", "Output format: json, table, csv, xml")
+	timeout     = flag.Duration("timeout", 10*time.Second, "HTTP timeout")
+	userAgent   = flag.String("user-agent", "wappalyzer-cli/1.0", "User agent string")
+	verbose     = flag.Bool("verbose", false, "Verbose output")
+	categories  = flag.Bool("categories", false, "Include category information")
+	info        = flag.Bool("info", false, "Include detailed app information")
+	outputFile  = flag.String("output-file", "-", "File to write results to (- for stdout)")
 )
 
+func init() {
+	flag.StringVar(outputFile, "o", "-", "Shorthand for -output-file")
+}
+
 type Result struct {
 	URL          string                     `json:"url"`
 	Title        string                     `json:"title,omitempty"`
@@ -35,18 +47,28 @@ type Result struct {
 func main() {
 	flag.Parse()
 
-	if *url == "" {
-		fmt.Fprintf(os.Stderr, "Usage: %s -url <URL>\n", os.Args[0])
+	if (*url == "") == (*input == "") {
+		fmt.Fprintf(os.Stderr, "Usage: %s -url <URL> | -input <file>\n", os.Args[0])
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
+	switch *output {
+	case "json", "table", "csv", "xml":
+	default:
+		log.Fatalf("Unknown output format: %s", *output)
+	}
+
 	client := &http.Client{Timeout: *timeout}
 	wappalyzerClient, err := wappalyzer.New()
 	if err != nil {
 		log.Fatalf("Failed to initialize wappalyzer: %v", err)
 	}
 
+	if *input != "" {
+		os.Exit(runBatch(*input, client, wappalyzerClient))
+	}
+
 	start := time.Now()
 	result, err := analyzeURL(*url, client, wappalyzerClient)
 	if err != nil {
@@ -54,15 +76,177 @@ func main() {
 	}
 	result.Duration = time.Since(start)
 
-	switch *output {
+	if err := writeOutput(*outputFile, *output, []*Result{result}, false); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
+}
+
+// runBatch analyzes every URL listed in the input file and returns the process exit code
+func runBatch(path string, client *http.Client, wappalyzerClient *wappalyzer.Wappalyze) int {
+	urls, err := readURLFile(path)
+	if err != nil {
+		log.Printf("Failed to read input: %v", err)
+		return 1
+	}
+
+	results, failures := analyzeURLs(urls, client, wappalyzerClient, *concurrency)
+	if err := writeOutput(*outputFile, *output, results, true); err != nil {
+		log.Printf("Failed to write results: %v", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Analyzed %d URLs: %d succeeded, %d failed\n", len(urls), len(results), failures)
+	if failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readURLFile reads URLs from path, or from stdin when path is "-"
+func readURLFile(path string) ([]string, error) {
+	if path == "-" {
+		return readURLs(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readURLs(f)
+}
+
+// readURLs returns one URL per line, skipping blank lines and # comments
+func readURLs(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
+
+// analyzeURLs analyzes the URLs across at most concurrency workers. Results are
+// returned in input order regardless of completion order; failures are logged
+// to stderr and counted.
+func analyzeURLs(urls []string, client *http.Client, wappalyzerClient *wappalyzer.Wappalyze, concurrency int) ([]*Result, int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Buffer outcomes by input index so output ordering is deterministic
+	results := make([]*Result, len(urls))
+	errs := make([]error, len(urls))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(urls); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				start := time.Now()
+				result, err := analyzeURL(urls[i], client, wappalyzerClient)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				result.Duration = time.Since(start)
+				results[i] = result
+			}
+		}()
+	}
+	for i := range urls {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var succeeded []*Result
+	failures := 0
+	for i, result := range results {
+		if errs[i] != nil {
+			log.Printf("Failed to analyze %s: %v", urls[i], errs[i])
+			failures++
+			continue
+		}
+		succeeded = append(succeeded, result)
+	}
+	return succeeded, failures
+}
+
+// writeOutput writes results to path, or stdout when path is "-". Parent
+// directories are created and an existing file is truncated.
+func writeOutput(path, format string, results []*Result, batch bool) (err error) {
+	if path == "" || path == "-" {
+		buffered := bufio.NewWriter(os.Stdout)
+		if err := writeResults(buffered, format, results, batch); err != nil {
+			return err
+		}
+		return buffered.Flush()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating output file: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("closing output file: %w", closeErr)
+		}
+	}()
+
+	buffered := bufio.NewWriter(f)
+	if err := writeResults(buffered, format, results, batch); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// writeResults writes results in the given format. Batch output is a JSON
+// array, CSV rows prefixed with the URL, one table section per URL, or a
+// <results> XML document.
+func writeResults(w io.Writer, format string, results []*Result, batch bool) error {
+	switch format {
 	case "json":
-		outputJSON(result)
+		if batch {
+			if results == nil {
+				results = []*Result{}
+			}
+			return outputJSON(w, results)
+		}
+		return outputJSON(w, results[0])
 	case "table":
-		outputTable(result)
+		for i, result := range results {
+			if i > 0 {
+				fmt.Fprintln(w, strings.Repeat("=", 50))
+			}
+			outputTable(w, result)
+		}
+		return nil
 	case "csv":
-		outputCSV(result)
+		if batch {
+			fmt.Fprint(w, "URL,")
+		}
+		fmt.Fprintln(w, "Technology,Description,Website,Categories")
+		for _, result := range results {
+			outputCSV(w, result, batch)
+		}
+		return nil
+	case "xml":
+		if batch {
+			return outputXML(w, newXMLResults(results))
+		}
+		return outputXML(w, newXMLResult(results[0]))
 	default:
-		log.Fatalf("Unknown output format: %s", *output)
+		return fmt.Errorf("unknown output format: %s", format)
 	}
 }
 
@@ -117,57 +301,130 @@ func analyzeURL(targetURL string, client *http.Client, wappalyzerClient *wappaly
 	return result, nil
 }
 
-func outputJSON(result *Result) {
-	encoder := json.NewEncoder(os.Stdout)
+func outputJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(result); err != nil {
-		log.Fatalf("Failed to encode JSON: %v", err)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
 	}
+	return nil
 }
 
-func outputTable(result *Result) {
-	fmt.Printf("URL: %s\n", result.URL)
+func outputTable(w io.Writer, result *Result) {
+	fmt.Fprintf(w, "URL: %s\n", result.URL)
 	if result.Title != "" {
-		fmt.Printf("Title: %s\n", result.Title)
+		fmt.Fprintf(w, "Title: %s\n", result.Title)
 	}
-	fmt.Printf("Analysis Duration: %v\n", result.Duration)
-	fmt.Printf("Timestamp: %s\n\n", result.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(w, "Analysis Duration: %v\n", result.Duration)
+	fmt.Fprintf(w, "Timestamp: %s\n\n", result.Timestamp.Format(time.RFC3339))
 
-	fmt.Println("Technologies Detected:")
-	fmt.Println(strings.Repeat("-", 50))
+	fmt.Fprintln(w, "Technologies Detected:")
+	fmt.Fprintln(w, strings.Repeat("-", 50))
 	
 	for tech, data := range result.Technologies {
-		fmt.Printf("• %s", tech)
+		fmt.Fprintf(w, "• %s", tech)
 		if *info {
 			if appInfo, ok := data.(wappalyzer.AppInfo); ok {
 				if appInfo.Description != "" {
-					fmt.Printf("\n  Description: %s", appInfo.Description)
+					fmt.Fprintf(w, "\n  Description: %s", appInfo.Description)
 				}
 				if appInfo.Website != "" {
-					fmt.Printf("\n  Website: %s", appInfo.Website)
+					fmt.Fprintf(w, "\n  Website: %s", appInfo.Website)
 				}
 				if len(appInfo.Categories) > 0 {
-					fmt.Printf("\n  Categories: %s", strings.Join(appInfo.Categories, ", "))
+					fmt.Fprintf(w, "\n  Categories: %s", strings.Join(appInfo.Categories, ", "))
 				}
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 }
 
-func outputCSV(result *Result) {
-	fmt.Println("Technology,Description,Website,Categories")
+func outputCSV(w io.Writer, result *Result, withURL bool) {
+	prefix := ""
+	if withURL {
+		prefix = fmt.Sprintf("\"%s\",", strings.ReplaceAll(result.URL, "\"", "\"\""))
+	}
 	for tech, data := range result.Technologies {
 		if *info {
 			if appInfo, ok := data.(wappalyzer.AppInfo); ok {
-				fmt.Printf("%s,\"%s\",\"%s\",\"%s\"\n",
+				fmt.Fprintf(w, "%s%s,\"%s\",\"%s\",\"%s\"\n",
+					prefix,
 					tech,
 					strings.ReplaceAll(appInfo.Description, "\"", "\"\""),
 					appInfo.Website,
 					strings.Join(appInfo.Categories, "; "))
 			}
 		} else {
-			fmt.Printf("%s,,,\n", tech)
+			fmt.Fprintf(w, "%s%s,,,\n", prefix, tech)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// xmlResults is the XML document for batch output
+type xmlResults struct {
+	XMLName xml.Name     `xml:"results"`
+	Results []*xmlResult `xml:"result"`
+}
+
+// xmlResult is the XML form of Result, with technologies as repeated elements
+type xmlResult struct {
+	XMLName      xml.Name        `xml:"result"`
+	URL          string          `xml:"url,attr"`
+	Title        string          `xml:"title,omitempty"`
+	Timestamp    time.Time       `xml:"timestamp"`
+	Duration     string          `xml:"duration"`
+	Technologies []xmlTechnology `xml:"technologies>technology"`
+}
+
+type xmlTechnology struct {
+	Name        string `xml:"name,attr"`
+	Description string `xml:"description,attr,omitempty"`
+	Website     string `xml:"website,attr,omitempty"`
+	Categories  string `xml:"categories,attr,omitempty"`
+}
+
+func newXMLResults(results []*Result) *xmlResults {
+	doc := &xmlResults{}
+	for _, result := range results {
+		doc.Results = append(doc.Results, newXMLResult(result))
+	}
+	return doc
+}
+
+func newXMLResult(result *Result) *xmlResult {
+	doc := &xmlResult{
+		URL:       result.URL,
+		Title:     result.Title,
+		Timestamp: result.Timestamp,
+		Duration:  result.Duration.String(),
+	}
+
+	for tech, data := range result.Technologies {
+		technology := xmlTechnology{Name: tech}
+		// Only -info results carry details; other results emit just the name
+		if appInfo, ok := data.(wappalyzer.AppInfo); ok {
+			technology.Description = appInfo.Description
+			technology.Website = appInfo.Website
+			technology.Categories = strings.Join(appInfo.Categories, ", ")
+		}
+		doc.Technologies = append(doc.Technologies, technology)
+	}
+	sort.Slice(doc.Technologies, func(i, j int) bool {
+		return doc.Technologies[i].Name < doc.Technologies[j].Name
+	})
+	return doc
+}
+
+func outputXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("encoding XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
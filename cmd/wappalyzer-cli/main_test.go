@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	wappalyzer "github.com/projectdiscovery/wappalyzergo"
+)
+
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="generator" content="WordPress 6.4"><title>Page ` + r.URL.Path + `</title></head><body></body></html>`))
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+func newTestWappalyzer(t *testing.T) *wappalyzer.Wappalyze {
+	t.Helper()
+
+	wappalyzerClient, err := wappalyzer.New()
+	if err != nil {
+		t.Fatalf("Failed to initialize wappalyzer: %v", err)
+	}
+	return wappalyzerClient
+}
+
+func writeURLFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write URL file: %v", err)
+	}
+	return path
+}
+
+func TestReadURLFile(t *testing.T) {
+	path := writeURLFile(t, "# sites to scan\nhttps://a.example\n\n   \n  https://b.example  \n# https://skipped.example\n")
+
+	urls, err := readURLFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"https://a.example", "https://b.example"}
+	if strings.Join(urls, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, urls)
+	}
+}
+
+func TestReadURLFileMissing(t *testing.T) {
+	if _, err := readURLFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected an error for a missing input file")
+	}
+}
+
+func TestAnalyzeURLsBatch(t *testing.T) {
+	site := newTestSite(t)
+	path := writeURLFile(t, site.URL+"/one\n# comment\n"+site.URL+"/two\nhttp://127.0.0.1:0/unreachable\n")
+
+	urls, err := readURLFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	results, failures := analyzeURLs(urls, client, newTestWappalyzer(t), 2)
+
+	if failures != 1 {
+		t.Errorf("Expected 1 failure, got %d", failures)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].URL != site.URL+"/one" || results[1].URL != site.URL+"/two" {
+		t.Errorf("Results should follow input order, got %s and %s", results[0].URL, results[1].URL)
+	}
+	if results[0].Title != "Page /one" {
+		t.Errorf("Unexpected title %q", results[0].Title)
+	}
+}
+
+func TestAnalyzeURLsConcurrency(t *testing.T) {
+	const total, limit = 12, 3
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		// Earlier URLs respond more slowly so completion order differs from input order
+		var n int
+		fmt.Sscanf(r.URL.Path, "/%d", &n)
+		time.Sleep(time.Duration(total-n) * 5 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>%d</title></head></html>", n)
+	}))
+	defer site.Close()
+
+	urls := make([]string, total)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/%d", site.URL, i)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	results, failures := analyzeURLs(urls, client, newTestWappalyzer(t), limit)
+
+	if failures != 0 {
+		t.Fatalf("Expected no failures, got %d", failures)
+	}
+	if len(results) != total {
+		t.Fatalf("Expected %d results, got %d", total, len(results))
+	}
+	for i, result := range results {
+		if result.URL != urls[i] || result.Title != fmt.Sprint(i) {
+			t.Errorf("Result %d out of order: %s (title %q)", i, result.URL, result.Title)
+		}
+	}
+
+	if maxInFlight > limit {
+		t.Errorf("Expected at most %d concurrent requests, saw %d", limit, maxInFlight)
+	}
+	if maxInFlight < 2 {
+		t.Errorf("Expected requests to run in parallel, saw at most %d at once", maxInFlight)
+	}
+}
+
+func TestWriteResultsBatchJSON(t *testing.T) {
+	results := []*Result{
+		{URL: "https://a.example", Technologies: map[string]interface{}{"Nginx": struct{}{}}},
+		{URL: "https://b.example", Technologies: map[string]interface{}{}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "json", results, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded []Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Batch JSON output should be an array: %v\n%s", err, buf.String())
+	}
+	if len(decoded) != 2 || decoded[1].URL != "https://b.example" {
+		t.Errorf("Unexpected batch JSON: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeResults(&buf, "json", nil, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("Expected an empty array when nothing succeeded, got %s", buf.String())
+	}
+}
+
+func TestWriteResultsBatchCSV(t *testing.T) {
+	results := []*Result{
+		{URL: "https://a.example/?q=1,2", Technologies: map[string]interface{}{"Nginx": struct{}{}}},
+		{URL: "https://b.example", Technologies: map[string]interface{}{"PHP": struct{}{}}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "csv", results, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "URL,Technology,Description,Website,Categories\n" +
+		"\"https://a.example/?q=1,2\",Nginx,,,\n" +
+		"\"https://b.example\",PHP,,,\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV output:\n%s", buf.String())
+	}
+}
+
+func TestWriteResultsBatchTable(t *testing.T) {
+	results := []*Result{
+		{URL: "https://a.example", Technologies: map[string]interface{}{}},
+		{URL: "https://b.example", Technologies: map[string]interface{}{}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "table", results, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Count(out, "Technologies Detected:") != 2 {
+		t.Errorf("Expected a table section per URL:\n%s", out)
+	}
+	if strings.Index(out, "URL: https://a.example") > strings.Index(out, "URL: https://b.example") {
+		t.Errorf("Table sections should follow input order:\n%s", out)
+	}
+}
+
+func TestWriteResultsXML(t *testing.T) {
+	result := &Result{
+		URL:   "https://example.com/?a=1&b=2",
+		Title: "Fish & <Chips>",
+		Technologies: map[string]interface{}{
+			"WordPress": wappalyzer.AppInfo{
+				Description: `A "free" CMS & <blog> tool`,
+				Website:     "https://wordpress.org",
+				Categories:  []string{"CMS", "Blogs"},
+			},
+			"Nginx": struct{}{},
+		},
+		Duration: 1500 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "xml", []*Result{result}, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Errorf("Expected an XML declaration:\n%s", out)
+	}
+	if !strings.Contains(out, `description="A &#34;free&#34; CMS &amp; &lt;blog&gt; tool"`) {
+		t.Errorf("Description should be escaped:\n%s", out)
+	}
+	if !strings.Contains(out, `<technology name="Nginx"></technology>`) {
+		t.Errorf("Technologies without details should emit just the name:\n%s", out)
+	}
+
+	var decoded xmlResult
+	if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v\n%s", err, out)
+	}
+	if decoded.URL != result.URL || decoded.Title != result.Title || decoded.Duration != "1.5s" {
+		t.Errorf("Unexpected round trip: %+v", decoded)
+	}
+	if len(decoded.Technologies) != 2 {
+		t.Fatalf("Expected 2 technologies, got %d", len(decoded.Technologies))
+	}
+	wordpress := decoded.Technologies[1]
+	if wordpress.Name != "WordPress" || wordpress.Description != `A "free" CMS & <blog> tool` || wordpress.Categories != "CMS, Blogs" {
+		t.Errorf("Unexpected technology: %+v", wordpress)
+	}
+}
+
+func TestWriteResultsBatchXML(t *testing.T) {
+	results := []*Result{
+		{URL: "https://a.example", Technologies: map[string]interface{}{"Nginx": struct{}{}}},
+		{URL: "https://b.example", Technologies: map[string]interface{}{}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "xml", results, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded xmlResults
+	if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v\n%s", err, buf.String())
+	}
+	if len(decoded.Results) != 2 || decoded.Results[1].URL != "https://b.example" {
+		t.Errorf("Unexpected batch XML:\n%s", buf.String())
+	}
+}
+
+func TestWriteOutputFile(t *testing.T) {
+	results := []*Result{
+		{URL: "https://a.example", Title: "A & B", Technologies: map[string]interface{}{"Nginx": struct{}{}}},
+	}
+
+	for _, format := range []string{"json", "table", "csv", "xml"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "exports", "nested", "results."+format)
+			if err := writeOutput(path, format, results, true); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			written, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+
+			var expected bytes.Buffer
+			if err := writeResults(&expected, format, results, true); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(written) != expected.String() {
+				t.Errorf("File contents differ from %s output:\n%s", format, written)
+			}
+		})
+	}
+}
+
+func TestWriteOutputTruncatesExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if err := os.WriteFile(path, []byte(strings.Repeat("stale line\n", 100)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeOutput(path, "csv", nil, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != "URL,Technology,Description,Website,Categories\n" {
+		t.Errorf("Existing file should be truncated, got:\n%s", written)
+	}
+}
+
+func TestWriteOutputUnwritablePath(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A regular file cannot be used as a parent directory
+	if err := writeOutput(filepath.Join(blocker, "results.json"), "json", nil, true); err == nil {
+		t.Error("Expected an error when the output directory cannot be created")
+	}
+}
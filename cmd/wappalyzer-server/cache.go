@@ -0,0 +1,91 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// resultCache is a size-bounded LRU cache of analysis responses with a fixed TTL
+type resultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[string]*list.Element
+	order   *list.List // front is the most recently used entry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	key      string
+	response AnalysisResponse
+	expires  time.Time
+}
+
+func newResultCache(maxSize int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// cacheKey identifies a request by URL and every option that affects the result
+func cacheKey(req AnalysisRequest) string {
+	return fmt.Sprintf("%s|info=%t|cats=%t|ua=%s", req.URL, req.WithInfo, req.WithCats, req.UserAgent)
+}
+
+// Get returns a copy of the cached response for key, if present and not expired
+func (c *resultCache) Get(key string) (*AnalysisResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	response := entry.response
+	return &response, true
+}
+
+// Set stores a copy of response under key, evicting the least recently used entry when full
+func (c *resultCache) Set(key string, response *AnalysisResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.response = *response
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, response: *response, expires: expires})
+
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of cached entries, including any not yet evicted after expiry
+func (c *resultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResultCacheTTL(t *testing.T) {
+	cache := newResultCache(10, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Set("a", &AnalysisResponse{URL: "https://a.example"})
+	if got, ok := cache.Get("a"); !ok || got.URL != "https://a.example" {
+		t.Fatalf("Expected cache hit for fresh entry, got %v, %v", got, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected expired entry to miss")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expired entry should be removed, cache has %d entries", cache.Len())
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResultCache(2, time.Minute)
+
+	cache.Set("a", &AnalysisResponse{URL: "a"})
+	cache.Set("b", &AnalysisResponse{URL: "b"})
+	cache.Get("a") // a is now more recently used than b
+	cache.Set("c", &AnalysisResponse{URL: "c"})
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected cache to be bounded at 2 entries, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Least recently used entry should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("Expected %q to remain cached", key)
+		}
+	}
+}
+
+func TestCacheKeyIncludesOptions(t *testing.T) {
+	base := AnalysisRequest{URL: "https://example.com"}
+	variants := []AnalysisRequest{
+		{URL: "https://example.com", WithInfo: true},
+		{URL: "https://example.com", WithCats: true},
+		{URL: "https://example.com", UserAgent: "custom"},
+		{URL: "https://example.org"},
+	}
+	for _, v := range variants {
+		if cacheKey(v) == cacheKey(base) {
+			t.Errorf("Request %+v should not share a cache key with %+v", v, base)
+		}
+	}
+}
+
+func TestHandleAnalyzeCaching(t *testing.T) {
+	var fetches int32
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Cached Site</title></head><body></body></html>`))
+	}))
+	defer site.Close()
+
+	server := newTestServer(t)
+	server.cache = newResultCache(10, time.Minute)
+
+	analyze := func(body string) AnalysisResponse {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/analyze", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		server.handleAnalyze(rr, req)
+
+		var response AnalysisResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	first := analyze(`{"url":"` + site.URL + `"}`)
+	if first.Cached {
+		t.Error("First request should not be served from cache")
+	}
+	if first.Title != "Cached Site" {
+		t.Errorf("Unexpected title %q", first.Title)
+	}
+
+	second := analyze(`{"url":"` + site.URL + `"}`)
+	if !second.Cached {
+		t.Error("Repeated request should be served from cache")
+	}
+	if second.Title != first.Title {
+		t.Errorf("Cached title %q does not match %q", second.Title, first.Title)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("Expected 1 fetch after a cache hit, got %d", n)
+	}
+
+	third := analyze(`{"url":"` + site.URL + `","with_info":true}`)
+	if third.Cached {
+		t.Error("Different options should miss the cache")
+	}
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("Expected 2 fetches after a cache miss, got %d", n)
+	}
+}
+
+func TestHandleAnalyzeDoesNotCacheErrors(t *testing.T) {
+	server := newTestServer(t)
+	server.cache = newResultCache(10, time.Minute)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	site.Close() // connections to the closed server fail
+
+	req := httptest.NewRequest("POST", "/api/analyze", strings.NewReader(`{"url":"`+site.URL+`"}`))
+	server.handleAnalyze(httptest.NewRecorder(), req)
+
+	if server.cache.Len() != 0 {
+		t.Errorf("Failed analyses should not be cached, cache has %d entries", server.cache.Len())
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// historyEntry summarizes a single analysis for the recent-analyses history
+type historyEntry struct {
+	URL             string        `json:"url"`
+	Timestamp       time.Time     `json:"timestamp"`
+	Duration        time.Duration `json:"duration"`
+	TechnologyCount int           `json:"technology_count"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// analysisHistory is a fixed-size ring buffer of the most recent analyses
+type analysisHistory struct {
+	mu      sync.Mutex
+	entries []historyEntry
+	next    int // index the next entry is written to
+	count   int
+}
+
+func newAnalysisHistory(size int) *analysisHistory {
+	return &analysisHistory{entries: make([]historyEntry, size)}
+}
+
+// Add records an entry, overwriting the oldest one once the buffer is full
+func (h *analysisHistory) Add(entry historyEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.count < len(h.entries) {
+		h.count++
+	}
+}
+
+// Entries returns a copy of the recorded entries, newest first
+func (h *analysisHistory) Entries() []historyEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := make([]historyEntry, 0, h.count)
+	for i := 1; i <= h.count; i++ {
+		entries = append(entries, h.entries[(h.next-i+len(h.entries))%len(h.entries)])
+	}
+	return entries
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAnalysisHistoryEviction(t *testing.T) {
+	history := newAnalysisHistory(3)
+	if got := history.Entries(); len(got) != 0 {
+		t.Fatalf("Expected empty history, got %d entries", len(got))
+	}
+
+	for i := 1; i <= 5; i++ {
+		history.Add(historyEntry{URL: fmt.Sprintf("https://%d.example", i)})
+	}
+
+	entries := history.Entries()
+	want := []string{"https://5.example", "https://4.example", "https://3.example"}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, url := range want {
+		if entries[i].URL != url {
+			t.Errorf("Entry %d: expected %s, got %s", i, url, entries[i].URL)
+		}
+	}
+}
+
+func TestAnalysisHistoryConcurrentAccess(t *testing.T) {
+	history := newAnalysisHistory(10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			history.Add(historyEntry{URL: fmt.Sprintf("https://%d.example", i)})
+			history.Entries()
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(history.Entries()); got != 10 {
+		t.Errorf("Expected history to hold 10 entries, got %d", got)
+	}
+}
+
+func TestHandleHistoryDisabled(t *testing.T) {
+	server := newTestServer(t)
+
+	rr := httptest.NewRecorder()
+	server.handleHistory(rr, httptest.NewRequest("GET", "/api/history", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 when history is disabled, got %d", rr.Code)
+	}
+}
+
+func TestHandleHistoryRecordsAnalyses(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>History Site</title></head><body></body></html>`))
+	}))
+	defer site.Close()
+
+	server := newTestServer(t)
+	server.history = newAnalysisHistory(50)
+
+	req := httptest.NewRequest("POST", "/api/analyze", strings.NewReader(`{"url":"`+site.URL+`"}`))
+	server.handleAnalyze(httptest.NewRecorder(), req)
+
+	rr := httptest.NewRecorder()
+	server.handleHistory(rr, httptest.NewRequest("GET", "/api/history", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var body struct {
+		Entries []historyEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode history: %v", err)
+	}
+	if len(body.Entries) != 1 {
+		t.Fatalf("Expected 1 history entry, got %d", len(body.Entries))
+	}
+	if body.Entries[0].URL != site.URL {
+		t.Errorf("Expected history URL %s, got %s", site.URL, body.Entries[0].URL)
+	}
+	if body.Entries[0].Timestamp.IsZero() {
+		t.Error("History entry should have a timestamp")
+	}
+
+	home := httptest.NewRecorder()
+	server.handleHome(home, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(home.Body.String(), "Recent Analyses") {
+		t.Error("Home page should render the history panel when history is enabled")
+	}
+}
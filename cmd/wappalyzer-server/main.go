@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"embed"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,19 +11,29 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	wappalyzer "github.com/projectdiscovery/wappalyzergo"
+	"github.com/webailyzer/webailyzer-lite-api/internal/buildinfo"
 )
 
 var (
-	port    = flag.Int("port", 8080, "Server port")
-	timeout = flag.Duration("timeout", 10*time.Second, "HTTP timeout for analysis requests")
+	port      = flag.Int("port", 8080, "Server port")
+	timeout   = flag.Duration("timeout", 10*time.Second, "HTTP timeout for analysis requests")
+	cacheTTL  = flag.Duration("cache-ttl", 5*time.Minute, "How long analysis results are cached (0 disables caching)")
+	cacheSize = flag.Int("cache-size", 100, "Maximum number of cached analysis results")
+	// History stores user-submitted URLs, so it is opt-in
+	historyEnabled = flag.Bool("history", false, "Keep a history of recent analyses at /api/history and on the home page")
+	historySize    = flag.Int("history-size", 50, "Number of recent analyses kept when -history is set")
 )
 
 type Server struct {
 	wappalyzer *wappalyzer.Wappalyze
 	client     *http.Client
+	cache      *resultCache     // nil when caching is disabled
+	history    *analysisHistory // nil when history is disabled
 }
 
 type AnalysisRequest struct {
@@ -38,6 +50,85 @@ type AnalysisResponse struct {
 	Timestamp    time.Time              `json:"timestamp"`
 	Duration     time.Duration          `json:"duration"`
 	Error        string                 `json:"error,omitempty"`
+	Cached       bool                   `json:"cached,omitempty"`
+}
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// templates holds the parsed UI templates: the home page and the results fragment
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// homePageData is the data rendered by the home page template
+type homePageData struct {
+	Request AnalysisRequest
+	Results *resultsView
+	History []historyEntry
+}
+
+// resultsView is the template-friendly form of an AnalysisResponse
+type resultsView struct {
+	Title        string
+	Error        string
+	Duration     time.Duration
+	Technologies []technologyView
+}
+
+// technologyView describes a single detected technology for display
+type technologyView struct {
+	Name        string
+	Description string
+	Website     string
+	Categories  []string
+}
+
+// newResultsView flattens an AnalysisResponse into a sorted list of technologies
+func newResultsView(response *AnalysisResponse) *resultsView {
+	view := &resultsView{
+		Title:    response.Title,
+		Error:    response.Error,
+		Duration: response.Duration,
+	}
+
+	categories := wappalyzer.GetCategoriesMapping()
+	for name, data := range response.Technologies {
+		tech := technologyView{Name: name}
+		switch info := data.(type) {
+		case wappalyzer.AppInfo:
+			tech.Description = info.Description
+			tech.Website = info.Website
+			tech.Categories = info.Categories
+		case wappalyzer.CatsInfo:
+			for _, id := range info.Cats {
+				if category, ok := categories[id]; ok {
+					tech.Categories = append(tech.Categories, category.Name)
+				}
+			}
+		}
+		view.Technologies = append(view.Technologies, tech)
+	}
+	sort.Slice(view.Technologies, func(i, j int) bool {
+		return view.Technologies[i].Name < view.Technologies[j].Name
+	})
+
+	return view
+}
+
+// renderTemplate executes the named template into a buffer so that errors can
+// still be reported with a proper status code before anything is written
+func renderTemplate(w http.ResponseWriter, name string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Failed to write %s: %v", name, err)
+	}
+	return nil
 }
 
 func main() {
@@ -52,134 +143,45 @@ func main() {
 		wappalyzer: wappalyzerClient,
 		client:     &http.Client{Timeout: *timeout},
 	}
+	if *cacheTTL > 0 && *cacheSize > 0 {
+		server.cache = newResultCache(*cacheSize, *cacheTTL)
+	}
+	if *historyEnabled && *historySize > 0 {
+		server.history = newAnalysisHistory(*historySize)
+	}
 
 	http.HandleFunc("/", server.handleHome)
 	http.HandleFunc("/api/analyze", server.handleAnalyze)
 	http.HandleFunc("/api/health", server.handleHealth)
 	http.HandleFunc("/api/stats", server.handleStats)
+	http.HandleFunc("/api/history", server.handleHistory)
 
 	log.Printf("Starting server on port %d", *port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), nil))
 }
 
 func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
-	tmpl := `<!DOCTYPE html>
-<html>
-<head>
-    <title>Wappalyzer Technology Detection</title>
-    <style>
-        body { font-family: Arial, sans-serif; max-width: 1200px; margin: 0 auto; padding: 20px; }
-        .form-group { margin-bottom: 15px; }
-        label { display: block; margin-bottom: 5px; font-weight: bold; }
-        input[type="url"], input[type="text"] { width: 100%; padding: 8px; border: 1px solid #ddd; border-radius: 4px; }
-        button { background: #007cba; color: white; padding: 10px 20px; border: none; border-radius: 4px; cursor: pointer; }
-        button:hover { background: #005a87; }
-        .results { margin-top: 20px; padding: 20px; background: #f5f5f5; border-radius: 4px; }
-        .tech-item { margin: 10px 0; padding: 10px; background: white; border-radius: 4px; }
-        .loading { display: none; color: #666; }
-        .error { color: #d32f2f; }
-        .success { color: #388e3c; }
-    </style>
-</head>
-<body>
-    <h1>Wappalyzer Technology Detection</h1>
-    <form id="analyzeForm">
-        <div class="form-group">
-            <label for="url">URL to analyze:</label>
-            <input type="url" id="url" name="url" required placeholder="https://example.com">
-        </div>
-        <div class="form-group">
-            <label for="userAgent">User Agent (optional):</label>
-            <input type="text" id="userAgent" name="userAgent" placeholder="Custom user agent">
-        </div>
-        <div class="form-group">
-            <label>
-                <input type="checkbox" id="withInfo" name="withInfo"> Include detailed information
-            </label>
-        </div>
-        <div class="form-group">
-            <label>
-                <input type="checkbox" id="withCats" name="withCats"> Include categories
-            </label>
-        </div>
-        <button type="submit">Analyze</button>
-        <div class="loading" id="loading">Analyzing...</div>
-    </form>
-
-    <div id="results" class="results" style="display: none;">
-        <h2>Results</h2>
-        <div id="resultsContent"></div>
-    </div>
-
-    <script>
-        document.getElementById('analyzeForm').addEventListener('submit', async function(e) {
-            e.preventDefault();
-            
-            const loading = document.getElementById('loading');
-            const results = document.getElementById('results');
-            const resultsContent = document.getElementById('resultsContent');
-            
-            loading.style.display = 'block';
-            results.style.display = 'none';
-            
-            const formData = new FormData(e.target);
-            const data = {
-                url: formData.get('url'),
-                user_agent: formData.get('userAgent') || '',
-                with_info: formData.has('withInfo'),
-                with_cats: formData.has('withCats')
-            };
-            
-            try {
-                const response = await fetch('/api/analyze', {
-                    method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify(data)
-                });
-                
-                const result = await response.json();
-                loading.style.display = 'none';
-                
-                if (result.error) {
-                    resultsContent.innerHTML = '<div class="error">Error: ' + result.error + '</div>';
-                } else {
-                    let html = '<div class="success">Analysis completed in ' + result.duration + '</div>';
-                    if (result.title) {
-                        html += '<p><strong>Title:</strong> ' + result.title + '</p>';
-                    }
-                    html += '<h3>Technologies Detected (' + Object.keys(result.technologies).length + '):</h3>';
-                    
-                    for (const [tech, info] of Object.entries(result.technologies)) {
-                        html += '<div class="tech-item">';
-                        html += '<strong>' + tech + '</strong>';
-                        if (info.description) {
-                            html += '<br><small>' + info.description + '</small>';
-                        }
-                        if (info.website) {
-                            html += '<br><a href="' + info.website + '" target="_blank">Website</a>';
-                        }
-                        if (info.categories && info.categories.length > 0) {
-                            html += '<br><em>Categories: ' + info.categories.join(', ') + '</em>';
-                        }
-                        html += '</div>';
-                    }
-                    
-                    resultsContent.innerHTML = html;
-                }
-                
-                results.style.display = 'block';
-            } catch (error) {
-                loading.style.display = 'none';
-                resultsContent.innerHTML = '<div class="error">Error: ' + error.message + '</div>';
-                results.style.display = 'block';
-            }
-        });
-    </script>
-</body>
-</html>`
-
-	t, _ := template.New("home").Parse(tmpl)
-	t.Execute(w, nil)
+	data := homePageData{
+		Request: AnalysisRequest{
+			URL:       r.URL.Query().Get("url"),
+			UserAgent: r.URL.Query().Get("userAgent"),
+			WithInfo:  r.URL.Query().Get("withInfo") != "",
+			WithCats:  r.URL.Query().Get("withCats") != "",
+		},
+	}
+
+	// Render results server-side when the form is submitted without JavaScript
+	if data.Request.URL != "" {
+		data.Results = newResultsView(s.analyze(data.Request))
+	}
+	if s.history != nil {
+		data.History = s.history.Entries()
+	}
+
+	if err := renderTemplate(w, "home.html", data); err != nil {
+		log.Printf("Failed to render home page: %v", err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+	}
 }
 
 func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
@@ -205,12 +207,45 @@ func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	response := s.analyze(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// analyze returns a cached result for the request when available, otherwise
+// analyzes the URL and caches successful results
+func (s *Server) analyze(req AnalysisRequest) *AnalysisResponse {
+	response := s.analyzeCached(req)
+	if s.history != nil {
+		s.history.Add(historyEntry{
+			URL:             req.URL,
+			Timestamp:       time.Now(),
+			Duration:        response.Duration,
+			TechnologyCount: len(response.Technologies),
+			Error:           response.Error,
+		})
+	}
+	return response
+}
+
+func (s *Server) analyzeCached(req AnalysisRequest) *AnalysisResponse {
+	key := cacheKey(req)
+	if s.cache != nil {
+		if cached, ok := s.cache.Get(key); ok {
+			cached.Cached = true
+			return cached
+		}
+	}
+
 	start := time.Now()
 	response := s.analyzeURL(req)
 	response.Duration = time.Since(start)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	if s.cache != nil && response.Error == "" {
+		s.cache.Set(key, response)
+	}
+	return response
 }
 
 func (s *Server) analyzeURL(req AnalysisRequest) *AnalysisResponse {
@@ -275,13 +310,27 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		http.Error(w, "History is disabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": s.history.Entries(),
+	})
+}
+
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	fingerprints := s.wappalyzer.GetFingerprints()
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"total_apps":      len(fingerprints.Apps),
-		"server_version":  "1.0.0",
+		"server_version":  buildinfo.Version,
+		"commit":          buildinfo.Commit,
+		"build_time":      buildinfo.BuildTime,
 		"last_updated":    time.Now().Format(time.RFC3339),
 	})
 }
\ No newline at end of file
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	wappalyzer "github.com/projectdiscovery/wappalyzergo"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	wappalyzerClient, err := wappalyzer.New()
+	if err != nil {
+		t.Fatalf("Failed to initialize wappalyzer: %v", err)
+	}
+
+	return &Server{
+		wappalyzer: wappalyzerClient,
+		client:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func TestResultsTemplate(t *testing.T) {
+	response := &AnalysisResponse{
+		URL:   "https://example.com",
+		Title: "Example <Domain>",
+		Technologies: map[string]interface{}{
+			"WordPress": wappalyzer.AppInfo{
+				Description: `WordPress is a "free" CMS & blogging tool.`,
+				Website:     "https://wordpress.org",
+				Categories:  []string{"CMS", "Blogs"},
+			},
+			"Nginx": struct{}{},
+		},
+		Duration: 1500 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "results", newResultsView(response)); err != nil {
+		t.Fatalf("Failed to render results template: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"Analysis completed in 1.5s",
+		"Example &lt;Domain&gt;",
+		"Technologies Detected (2):",
+		"<strong>Nginx</strong>",
+		"<strong>WordPress</strong>",
+		"WordPress is a &#34;free&#34; CMS &amp; blogging tool.",
+		`<a href="https://wordpress.org" target="_blank">Website</a>`,
+		"Categories: CMS, Blogs",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Rendered results missing %q:\n%s", want, out)
+		}
+	}
+
+	// Technologies are listed in name order
+	if strings.Index(out, "Nginx") > strings.Index(out, "WordPress") {
+		t.Error("Technologies should be sorted by name")
+	}
+}
+
+func TestResultsTemplateError(t *testing.T) {
+	response := &AnalysisResponse{Error: "Failed to fetch URL: <timeout>"}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "results", newResultsView(response)); err != nil {
+		t.Fatalf("Failed to render results template: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `<div class="error">Error: Failed to fetch URL: &lt;timeout&gt;</div>`) {
+		t.Errorf("Unexpected error rendering:\n%s", buf.String())
+	}
+}
+
+func TestHandleHome(t *testing.T) {
+	server := newTestServer(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	server.handleHome(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+
+	body := rr.Body.String()
+	if !strings.Contains(body, `<form id="analyzeForm" method="get" action="/">`) {
+		t.Error("Home page should contain the analyze form")
+	}
+	if !strings.Contains(body, `<div id="results" class="results" style="display: none;">`) {
+		t.Error("Results panel should be hidden without a submitted URL")
+	}
+}
+
+func TestHandleHomeServerSideResults(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Rendered Site</title></head><body></body></html>`))
+	}))
+	defer site.Close()
+
+	server := newTestServer(t)
+
+	req := httptest.NewRequest("GET", "/?url="+site.URL+"&withInfo=on", nil)
+	rr := httptest.NewRecorder()
+	server.handleHome(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	body := rr.Body.String()
+	if strings.Contains(body, `style="display: none;"`) {
+		t.Error("Results panel should be visible when results are rendered")
+	}
+	if !strings.Contains(body, "Technologies Detected (") {
+		t.Error("Server-rendered results should be included in the page")
+	}
+	if !strings.Contains(body, `value="`+site.URL+`"`) {
+		t.Error("Submitted URL should be kept in the form")
+	}
+	if !strings.Contains(body, `name="withInfo" checked`) {
+		t.Error("Submitted options should be kept in the form")
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Accessibility issues reported by analyzeAccessibility
+const (
+	a11yIssueInvalidRole     = "invalid_aria_role"
+	a11yIssueRedundantRole   = "redundant_aria_role"
+	a11yIssueBrokenReference = "broken_aria_reference"
+	a11yIssueHeadingSkipped  = "heading_level_skipped"
+	a11yIssueDuplicateID     = "duplicate_id"
+	a11yIssueAutoplay        = "autoplay_media"
+	a11yIssueMissingCaptions = "missing_captions"
+	a11yIssueUntitledFrame   = "missing_iframe_title"
+	a11yIssueEmptyLink       = "empty_link"
+	a11yIssueEmptyButton     = "empty_button"
+)
+
+// snippetMaxLen caps the start tags quoted in accessibility issues
+const snippetMaxLen = 120
+
+// AccessibilityReport lists the accessibility problems found in a page's markup
+type AccessibilityReport struct {
+	Issues []AccessibilityIssue `json:"issues"`
+	// Headings is the page outline, in document order
+	Headings []Heading   `json:"headings"`
+	Media    MediaReport `json:"media"`
+}
+
+// MediaReport counts the page's <video> and <audio> elements
+type MediaReport struct {
+	Elements int `json:"elements"`
+	// Autoplay counts media that start playing sound on their own
+	Autoplay int `json:"autoplay"`
+	// Uncaptioned counts media with sound but no captions or subtitles track
+	Uncaptioned int `json:"uncaptioned"`
+}
+
+// Heading is one h1-h6 element and its visible text
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// AccessibilityIssue is one problem, tied to the WCAG success criterion it fails
+type AccessibilityIssue struct {
+	Type     string `json:"type"`
+	WCAG     string `json:"wcag"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Element quotes the offending start tag as written, shortened to snippetMaxLen
+	Element string `json:"element,omitempty"`
+}
+
+// ariaRoles are the WAI-ARIA 1.2 roles a page may use; DPUB roles (doc-*) are also
+// accepted
+var ariaRoles = toSet([]string{
+	"alert", "alertdialog", "application", "article", "banner", "blockquote", "button",
+	"caption", "cell", "checkbox", "code", "columnheader", "combobox", "complementary",
+	"contentinfo", "definition", "deletion", "dialog", "directory", "document", "emphasis",
+	"feed", "figure", "form", "generic", "graphics-document", "graphics-object",
+	"graphics-symbol", "grid", "gridcell", "group", "heading", "img", "insertion", "link",
+	"list", "listbox", "listitem", "log", "main", "marquee", "math", "menu", "menubar",
+	"menuitem", "menuitemcheckbox", "menuitemradio", "meter", "navigation", "none", "note",
+	"option", "paragraph", "presentation", "progressbar", "radio", "radiogroup", "region",
+	"row", "rowgroup", "rowheader", "scrollbar", "search", "searchbox", "separator",
+	"slider", "spinbutton", "status", "strong", "subscript", "superscript", "switch", "tab",
+	"table", "tablist", "tabpanel", "term", "textbox", "time", "timer", "toolbar", "tooltip",
+	"tree", "treegrid", "treeitem",
+})
+
+// implicitRoles are the roles elements have without a role attribute, for elements
+// whose implicit role does not depend on their attributes
+var implicitRoles = map[string]string{
+	"article": "article", "aside": "complementary", "button": "button", "dialog": "dialog",
+	"h1": "heading", "h2": "heading", "h3": "heading", "h4": "heading", "h5": "heading",
+	"h6": "heading", "hr": "separator", "li": "listitem", "main": "main", "nav": "navigation",
+	"ol": "list", "progress": "progressbar", "table": "table", "textarea": "textbox",
+	"tr": "row", "ul": "list",
+}
+
+// implicitInputRoles are the implicit roles of <input> by type
+var implicitInputRoles = map[string]string{
+	"button": "button", "checkbox": "checkbox", "radio": "radio", "range": "slider",
+	"reset": "button", "submit": "button",
+}
+
+// ariaReferenceAttrs name elements by id and break when the id does not exist
+var ariaReferenceAttrs = []string{"aria-labelledby", "aria-describedby"}
+
+// ariaReference is an id an element refers to through an ARIA attribute
+type ariaReference struct {
+	attr, id, element string
+}
+
+// openHeading is a heading whose text is still being read
+type openHeading struct {
+	level   int
+	element string
+	text    []string
+}
+
+// openControl is a link or button whose content is still being read
+type openControl struct {
+	tag, element string
+	// checked is unset for links without href, which are not controls
+	checked bool
+	// named is set once the control has text, an aria-label or an image with alt text
+	named bool
+}
+
+// openMedia is a <video> or <audio> element whose tracks are still being read
+type openMedia struct {
+	tag, element string
+	muted        bool
+	captioned    bool
+}
+
+// analyzeAccessibility checks the page markup for accessibility problems. ARIA
+// references and duplicate ids are checked once the whole page has been read, since
+// references may point to elements further down.
+func analyzeAccessibility(body []byte) *AccessibilityReport {
+	report := &AccessibilityReport{Issues: []AccessibilityIssue{}, Headings: []Heading{}}
+	ids := make(map[string]int)
+	var (
+		idOrder    []string // ids in the order first seen
+		references []ariaReference
+		heading    *openHeading
+		media      *openMedia
+		controls   []*openControl // open links and buttons, innermost last
+		hidden     int            // depth of open hidden elements
+	)
+
+	// closeHeading records the open heading, flagging it when it is more than one
+	// level below the heading before it
+	closeHeading := func() {
+		if heading == nil {
+			return
+		}
+		if n := len(report.Headings); n > 0 {
+			if previous := report.Headings[n-1].Level; heading.level > previous+1 {
+				report.Issues = append(report.Issues, AccessibilityIssue{
+					Type:     a11yIssueHeadingSkipped,
+					WCAG:     "1.3.1",
+					Severity: "medium",
+					Message:  fmt.Sprintf("h%d follows h%d; use h%d so the outline does not skip levels", heading.level, previous, previous+1),
+					Element:  heading.element,
+				})
+			}
+		}
+		report.Headings = append(report.Headings, Heading{Level: heading.level, Text: strings.Join(heading.text, " ")})
+		heading = nil
+	}
+
+	// closeControl flags the innermost open control with the given tag when nothing
+	// gave it a name
+	closeControl := func(tag string) {
+		for i := len(controls) - 1; i >= 0; i-- {
+			if controls[i].tag != tag {
+				continue
+			}
+			control := controls[i]
+			controls = append(controls[:i], controls[i+1:]...)
+			if control.checked && !control.named {
+				report.Issues = append(report.Issues, emptyControlIssue(control))
+			}
+			return
+		}
+	}
+
+	// closeMedia flags the open media element when it has sound but no captions.
+	// Muted video has no sound to caption.
+	closeMedia := func() {
+		if media == nil {
+			return
+		}
+		if !media.captioned && !(media.muted && media.tag == "video") {
+			report.Media.Uncaptioned++
+			report.Issues = append(report.Issues, AccessibilityIssue{
+				Type:     a11yIssueMissingCaptions,
+				WCAG:     "1.2.2",
+				Severity: "high",
+				Message:  fmt.Sprintf(`<%s> has no <track kind="captions"> or subtitles, so its audio is lost on visitors who cannot hear it`, media.tag),
+				Element:  media.element,
+			})
+		}
+		media = nil
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF at the end of the body; the tokenizer recovers from malformed markup itself
+			closeHeading()
+			closeMedia()
+			for len(controls) > 0 {
+				closeControl(controls[len(controls)-1].tag)
+			}
+			var duplicates []string
+			for _, id := range idOrder {
+				if ids[id] > 1 {
+					duplicates = append(duplicates, fmt.Sprintf("%q (%d times)", id, ids[id]))
+				}
+			}
+			if len(duplicates) > 0 {
+				report.Issues = append(report.Issues, AccessibilityIssue{
+					Type:     a11yIssueDuplicateID,
+					WCAG:     "4.1.1",
+					Severity: "medium",
+					Message:  "ids used more than once, which breaks labels and ARIA references to them: " + strings.Join(duplicates, ", "),
+				})
+			}
+			for _, ref := range references {
+				if ids[ref.id] == 0 {
+					report.Issues = append(report.Issues, AccessibilityIssue{
+						Type:     a11yIssueBrokenReference,
+						WCAG:     "4.1.2",
+						Severity: "high",
+						Message:  fmt.Sprintf("%s refers to id %q, which is not on the page", ref.attr, ref.id),
+						Element:  ref.element,
+					})
+				}
+			}
+			return report
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// Raw must be read before TagName, which lowercases the name in place
+			element := elementSnippet(string(z.Raw()))
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			attrs := tagAttrs(z, hasAttr)
+
+			if id := attrs["id"]; id != "" {
+				if ids[id] == 0 {
+					idOrder = append(idOrder, id)
+				}
+				ids[id]++
+			}
+			for _, attr := range ariaReferenceAttrs {
+				for _, id := range strings.Fields(attrs[attr]) {
+					references = append(references, ariaReference{attr: attr, id: id, element: element})
+				}
+			}
+			if role, ok := attrs["role"]; ok {
+				report.Issues = append(report.Issues, checkRole(tag, strings.ToLower(role), attrs, element)...)
+			}
+			if hiddenElements[tag] {
+				hidden++
+			}
+			if level := headingLevel(tag); level > 0 {
+				closeHeading()
+				heading = &openHeading{level: level, element: element}
+			}
+			switch tag {
+			case "video", "audio":
+				closeMedia()
+				_, muted := attrs["muted"]
+				media = &openMedia{tag: tag, element: element, muted: muted}
+				report.Media.Elements++
+				if issue, ok := checkAutoplay(tag, attrs, element); ok {
+					report.Media.Autoplay++
+					report.Issues = append(report.Issues, issue)
+				}
+			case "track":
+				if kind := strings.ToLower(attrs["kind"]); media != nil && (kind == "captions" || kind == "subtitles") {
+					media.captioned = true
+				}
+			case "a", "button":
+				_, href := attrs["href"]
+				controls = append(controls, &openControl{
+					tag:     tag,
+					element: element,
+					checked: tag == "button" || href,
+					named:   strings.TrimSpace(attrs["aria-label"]) != "" || strings.TrimSpace(attrs["aria-labelledby"]) != "",
+				})
+			case "img":
+				if strings.TrimSpace(attrs["alt"]) != "" {
+					for _, control := range controls {
+						control.named = true
+					}
+				}
+			case "iframe":
+				if strings.TrimSpace(attrs["title"]) == "" && !isHiddenElement(attrs) {
+					report.Issues = append(report.Issues, AccessibilityIssue{
+						Type:     a11yIssueUntitledFrame,
+						WCAG:     "4.1.2",
+						Severity: "medium",
+						Message:  "<iframe> has no title, so screen readers cannot say what it contains",
+						Element:  element,
+					})
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if hiddenElements[tag] && hidden > 0 {
+				hidden--
+			}
+			if headingLevel(tag) > 0 {
+				closeHeading()
+			}
+			switch tag {
+			case "video", "audio":
+				closeMedia()
+			case "a", "button":
+				closeControl(tag)
+			}
+		case html.TextToken:
+			if hidden > 0 {
+				continue
+			}
+			text := strings.Fields(string(z.Text()))
+			if heading != nil {
+				heading.text = append(heading.text, text...)
+			}
+			if len(text) > 0 {
+				for _, control := range controls {
+					control.named = true
+				}
+			}
+		}
+	}
+}
+
+// headingLevel returns 1-6 for h1-h6 and 0 for any other tag
+func headingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
+
+// emptyControlIssue describes a link or button that screen readers can only announce
+// by its role
+func emptyControlIssue(control *openControl) AccessibilityIssue {
+	if control.tag == "button" {
+		return AccessibilityIssue{
+			Type:     a11yIssueEmptyButton,
+			WCAG:     "4.1.2",
+			Severity: "high",
+			Message:  "<button> has no text, aria-label or image alt text, so screen readers announce it only as \"button\"",
+			Element:  control.element,
+		}
+	}
+	return AccessibilityIssue{
+		Type:     a11yIssueEmptyLink,
+		WCAG:     "2.4.4",
+		Severity: "high",
+		Message:  "Link has no text, aria-label or image alt text, so screen readers cannot say where it leads",
+		Element:  control.element,
+	}
+}
+
+// isHiddenElement reports whether an element is hidden from every visitor through
+// its attributes, such as a tracking iframe with style="display:none"
+func isHiddenElement(attrs map[string]string) bool {
+	if _, hidden := attrs["hidden"]; hidden {
+		return true
+	}
+	style := strings.Join(strings.Fields(strings.ToLower(attrs["style"])), "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// checkAutoplay flags media that play sound without the visitor starting them. Muted
+// video autoplays silently, as background video commonly does, and is allowed.
+func checkAutoplay(tag string, attrs map[string]string, element string) (AccessibilityIssue, bool) {
+	if _, autoplay := attrs["autoplay"]; !autoplay {
+		return AccessibilityIssue{}, false
+	}
+	if _, muted := attrs["muted"]; muted && tag == "video" {
+		return AccessibilityIssue{}, false
+	}
+	return AccessibilityIssue{
+		Type:     a11yIssueAutoplay,
+		WCAG:     "1.4.2",
+		Severity: "high",
+		Message:  fmt.Sprintf("<%s> plays automatically; remove autoplay and let visitors start playback, or provide a control to stop it", tag),
+		Element:  element,
+	}, true
+}
+
+// checkRole flags unknown role tokens and a role the element already has implicitly
+func checkRole(tag, role string, attrs map[string]string, element string) []AccessibilityIssue {
+	var issues []AccessibilityIssue
+	for _, token := range strings.Fields(role) {
+		if !ariaRoles[token] && !strings.HasPrefix(token, "doc-") {
+			issues = append(issues, AccessibilityIssue{
+				Type:     a11yIssueInvalidRole,
+				WCAG:     "4.1.2",
+				Severity: "high",
+				Message:  fmt.Sprintf("role %q is not a WAI-ARIA role, so assistive technology ignores it", token),
+				Element:  element,
+			})
+		}
+	}
+	if implicit := implicitRole(tag, attrs); implicit != "" && strings.TrimSpace(role) == implicit {
+		issues = append(issues, AccessibilityIssue{
+			Type:     a11yIssueRedundantRole,
+			WCAG:     "4.1.2",
+			Severity: "low",
+			Message:  fmt.Sprintf("<%s> already has the %s role; remove role=%q", tag, implicit, implicit),
+			Element:  element,
+		})
+	}
+	return issues
+}
+
+// implicitRole returns the role an element has without a role attribute, or "" when
+// it has none or the role depends on more than the tag
+func implicitRole(tag string, attrs map[string]string) string {
+	switch tag {
+	case "a", "area":
+		if _, ok := attrs["href"]; ok {
+			return "link"
+		}
+		return ""
+	case "input":
+		return implicitInputRoles[strings.ToLower(attrs["type"])]
+	}
+	return implicitRoles[tag]
+}
+
+// tagAttrs reads the current tag's attributes; the first of a repeated attribute wins,
+// as in browsers
+func tagAttrs(z *html.Tokenizer, hasAttr bool) map[string]string {
+	attrs := make(map[string]string)
+	for hasAttr {
+		var key, value []byte
+		key, value, hasAttr = z.TagAttr()
+		if _, seen := attrs[string(key)]; !seen {
+			attrs[string(key)] = string(value)
+		}
+	}
+	return attrs
+}
+
+// elementSnippet returns a start tag as written, with whitespace collapsed and
+// shortened to snippetMaxLen
+func elementSnippet(raw string) string {
+	snippet := []rune(strings.Join(strings.Fields(raw), " "))
+	if len(snippet) > snippetMaxLen {
+		return string(snippet[:snippetMaxLen-3]) + "..."
+	}
+	return string(snippet)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// issueTypes returns the issue types in report order
+func issueTypes(report *AccessibilityReport) []string {
+	types := []string{}
+	for _, issue := range report.Issues {
+		types = append(types, issue.Type)
+	}
+	return types
+}
+
+func TestAnalyzeAccessibilityARIA(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "valid references and roles",
+			body: `<h2 id="title">Cart</h2><p id="hint">Two items</p>
+				<section role="region" aria-labelledby="title" aria-describedby="hint"></section>
+				<div role="button">Buy</div><aside role="doc-tip"></aside>`,
+			want: []string{},
+		},
+		{
+			name: "reference to a later element",
+			body: `<input aria-labelledby="label"><label id="label">Email</label>`,
+			want: []string{},
+		},
+		{
+			name: "broken references",
+			body: `<label id="email">Email</label><input aria-labelledby="email missing"><div aria-describedby="gone"></div>`,
+			want: []string{a11yIssueBrokenReference, a11yIssueBrokenReference},
+		},
+		{
+			name: "unknown role",
+			body: `<div role="buton">Buy</div><span role="presentation fancy"></span>`,
+			want: []string{a11yIssueInvalidRole, a11yIssueInvalidRole},
+		},
+		{
+			name: "redundant roles",
+			body: `<button role="button">Buy</button><a href="/" role="link">Home</a><input type="checkbox" role="checkbox"><nav role="NAVIGATION"></nav>`,
+			want: []string{a11yIssueRedundantRole, a11yIssueRedundantRole, a11yIssueRedundantRole, a11yIssueRedundantRole},
+		},
+		{
+			name: "role changing the element",
+			body: `<a role="button">Open</a><ul role="menu"></ul>`,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeAccessibility([]byte(tt.body))
+			if got := issueTypes(report); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected issues %v, got %+v", tt.want, report.Issues)
+			}
+			for _, issue := range report.Issues {
+				if issue.WCAG != "4.1.2" || issue.Element == "" {
+					t.Errorf("expected a WCAG 4.1.2 issue quoting its element, got %+v", issue)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeAccessibilityHeadingOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		headings []Heading
+		skipped  int
+	}{
+		{
+			name:     "proper nesting",
+			body:     `<h1>Bakery</h1><h2>Breads</h2><h3>Sourdough</h3>`,
+			headings: []Heading{{1, "Bakery"}, {2, "Breads"}, {3, "Sourdough"}},
+		},
+		{
+			name:     "skipped level",
+			body:     `<h1>Bakery</h1><h3>Sourdough</h3>`,
+			headings: []Heading{{1, "Bakery"}, {3, "Sourdough"}},
+			skipped:  1,
+		},
+		{
+			name: "multiple sections",
+			body: `<h1>Bakery</h1><h2>Breads</h2><h3>Rye</h3><h2>Pastries</h2><h3>Croissants</h3>
+				<h2>Visit <em>us</em><script>track()</script></h2><h4>Parking</h4>`,
+			headings: []Heading{{1, "Bakery"}, {2, "Breads"}, {3, "Rye"}, {2, "Pastries"}, {3, "Croissants"}, {2, "Visit us"}, {4, "Parking"}},
+			skipped:  1,
+		},
+		{
+			name:     "no headings",
+			body:     `<p>Plain text</p>`,
+			headings: []Heading{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeAccessibility([]byte(tt.body))
+			if !reflect.DeepEqual(report.Headings, tt.headings) {
+				t.Errorf("expected headings %v, got %v", tt.headings, report.Headings)
+			}
+			skipped := 0
+			for _, issue := range report.Issues {
+				if issue.Type == a11yIssueHeadingSkipped && issue.WCAG == "1.3.1" {
+					skipped++
+				}
+			}
+			if skipped != tt.skipped || len(report.Issues) != tt.skipped {
+				t.Errorf("expected %d skipped levels, got %+v", tt.skipped, report.Issues)
+			}
+		})
+	}
+}
+
+func TestAnalyzeAccessibilityDuplicateIDs(t *testing.T) {
+	unique := analyzeAccessibility([]byte(`<label for="email" id="email-label">Email</label><input id="email">`))
+	if len(unique.Issues) != 0 {
+		t.Errorf("expected no issues for unique ids, got %+v", unique.Issues)
+	}
+
+	report := analyzeAccessibility([]byte(`<div id="promo"></div><input id="email"><div id="promo"></div>
+		<input id="email"><p id="promo"></p><span id="unique"></span>`))
+	if !reflect.DeepEqual(issueTypes(report), []string{a11yIssueDuplicateID}) {
+		t.Fatalf("expected one duplicate_id issue, got %+v", report.Issues)
+	}
+	issue := report.Issues[0]
+	if issue.WCAG != "4.1.1" || !strings.HasSuffix(issue.Message, `"promo" (3 times), "email" (2 times)`) {
+		t.Errorf("expected a WCAG 4.1.1 issue listing the repeated ids, got %+v", issue)
+	}
+}
+
+// captions is a captions track for media in tests that check something else
+const captions = `<track kind="captions" src="captions.vtt" srclang="en">`
+
+func TestAnalyzeAccessibilityAutoplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		autoplay int
+	}{
+		{name: "autoplaying video", body: `<video src="intro.mp4" autoplay>` + captions + `</video>`, autoplay: 1},
+		{name: "autoplaying audio", body: `<audio src="theme.mp3" autoplay="autoplay">` + captions + `</audio>`, autoplay: 1},
+		{name: "muted autoplaying video", body: `<video src="background.mp4" autoplay muted loop></video>`},
+		{name: "muted autoplaying audio", body: `<audio src="theme.mp3" autoplay muted>` + captions + `</audio>`, autoplay: 1},
+		{name: "user started media", body: `<video src="intro.mp4" controls>` + captions + `</video><audio src="theme.mp3" controls>` + captions + `</audio>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeAccessibility([]byte(tt.body))
+			if report.Media.Autoplay != tt.autoplay || len(report.Issues) != tt.autoplay {
+				t.Errorf("expected %d autoplaying media, got %+v (issues %+v)", tt.autoplay, report.Media, report.Issues)
+			}
+			for _, issue := range report.Issues {
+				if issue.Type != a11yIssueAutoplay || issue.WCAG != "1.4.2" {
+					t.Errorf("expected a WCAG 1.4.2 autoplay_media issue, got %+v", issue)
+				}
+			}
+			if report.Media.Elements == 0 {
+				t.Error("expected the media to be counted")
+			}
+		})
+	}
+}
+
+func TestAnalyzeAccessibilityCaptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		uncaptioned []string
+	}{
+		{
+			name: "captioned media",
+			body: `<video controls><source src="intro.mp4">` + captions + `</video>
+				<audio controls><track kind="Subtitles" src="de.vtt" srclang="de"></audio>`,
+		},
+		{
+			name:        "uncaptioned media",
+			body:        `<video controls src="intro.mp4"></video><audio controls><source src="theme.mp3"><track kind="chapters" src="chapters.vtt"></audio>`,
+			uncaptioned: []string{`<video controls src="intro.mp4">`, `<audio controls>`},
+		},
+		{
+			name:        "track after the media element",
+			body:        `<video src="intro.mp4"></video>` + captions,
+			uncaptioned: []string{`<video src="intro.mp4">`},
+		},
+		{
+			name:        "unclosed media",
+			body:        `<video src="a.mp4"><video src="b.mp4">` + captions,
+			uncaptioned: []string{`<video src="a.mp4">`},
+		},
+		{name: "muted video", body: `<video src="background.mp4" muted loop></video>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeAccessibility([]byte(tt.body))
+			var elements []string
+			for _, issue := range report.Issues {
+				if issue.Type != a11yIssueMissingCaptions || issue.WCAG != "1.2.2" {
+					t.Errorf("expected only WCAG 1.2.2 missing_captions issues, got %+v", issue)
+				}
+				elements = append(elements, issue.Element)
+			}
+			if !reflect.DeepEqual(elements, tt.uncaptioned) || report.Media.Uncaptioned != len(tt.uncaptioned) {
+				t.Errorf("expected uncaptioned media %v, got %v (media %+v)", tt.uncaptioned, elements, report.Media)
+			}
+		})
+	}
+}
+
+func TestAnalyzeAccessibilityIframeTitles(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		untitled int
+	}{
+		{name: "titled", body: `<iframe src="https://maps.example/embed" title="Map to the bakery"></iframe>`},
+		{name: "untitled", body: `<iframe src="https://maps.example/embed"></iframe><iframe src="/ad" title="  "></iframe>`, untitled: 2},
+		{
+			name: "hidden",
+			body: `<iframe src="https://tag.example/ns" style="display: none; visibility: hidden"></iframe>
+				<iframe src="https://tag.example/sync" STYLE="width:0;DISPLAY:NONE"></iframe><iframe src="/pixel" hidden></iframe>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeAccessibility([]byte(tt.body))
+			if len(report.Issues) != tt.untitled {
+				t.Fatalf("expected %d untitled iframes, got %+v", tt.untitled, report.Issues)
+			}
+			for _, issue := range report.Issues {
+				if issue.Type != a11yIssueUntitledFrame || issue.WCAG != "4.1.2" || !strings.HasPrefix(issue.Element, "<iframe") {
+					t.Errorf("expected a WCAG 4.1.2 missing_iframe_title issue quoting the iframe, got %+v", issue)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeAccessibilityQuotesElement(t *testing.T) {
+	report := analyzeAccessibility([]byte(`<DIV  role="buton"
+		class="cta">Buy</DIV>`))
+	if len(report.Issues) != 1 || report.Issues[0].Element != `<DIV role="buton" class="cta">` {
+		t.Errorf("expected the start tag as written, got %+v", report.Issues)
+	}
+}
+
+func TestAnalyzeHandlerReportsAccessibility(t *testing.T) {
+	rr := runPipeline(t, map[string]interface{}{
+		"html":      `<html><body><input aria-describedby="help"></body></html>`,
+		"analyzers": []string{"accessibility"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Accessibility == nil || !reflect.DeepEqual(issueTypes(response.Accessibility), []string{a11yIssueBrokenReference}) {
+		t.Errorf("expected a broken_aria_reference issue, got %+v", response.Accessibility)
+	}
+}
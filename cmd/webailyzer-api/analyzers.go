@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Analyzer inspects a page after technology detection and records its results on the
+// response. Analyzers must not keep references to the page, whose body is released
+// once every analyzer has run.
+type Analyzer interface {
+	// Name identifies the analyzer and keys its entry in the response timings
+	Name() string
+	Analyze(page *fetchedPage, result *AnalyzeResponse)
+}
+
+// analyzerFunc adapts a function to the Analyzer interface
+type analyzerFunc struct {
+	name string
+	fn   func(page *fetchedPage, result *AnalyzeResponse)
+}
+
+func (a analyzerFunc) Name() string { return a.name }
+
+func (a analyzerFunc) Analyze(page *fetchedPage, result *AnalyzeResponse) { a.fn(page, result) }
+
+// AnalyzerRegistry holds the analyzers run for every page, in registration order
+type AnalyzerRegistry struct {
+	mu        sync.RWMutex
+	analyzers []Analyzer
+	byName    map[string]Analyzer
+}
+
+// NewAnalyzerRegistry creates a registry holding the given analyzers
+func NewAnalyzerRegistry(analyzers ...Analyzer) *AnalyzerRegistry {
+	r := &AnalyzerRegistry{byName: make(map[string]Analyzer)}
+	for _, a := range analyzers {
+		r.Register(a)
+	}
+	return r
+}
+
+// Register adds an analyzer to run after those already registered. It panics if the
+// name is empty or already taken, since that is a programming error.
+func (r *AnalyzerRegistry) Register(a Analyzer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := a.Name()
+	if name == "" {
+		panic("analyzer name must not be empty")
+	}
+	if _, exists := r.byName[name]; exists {
+		panic(fmt.Sprintf("analyzer %q is already registered", name))
+	}
+	r.analyzers = append(r.analyzers, a)
+	r.byName[name] = a
+}
+
+// Lookup returns the analyzer registered under name
+func (r *AnalyzerRegistry) Lookup(name string) (Analyzer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.byName[name]
+	return a, ok
+}
+
+// All returns the registered analyzers in the order they run
+func (r *AnalyzerRegistry) All() []Analyzer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Analyzer(nil), r.analyzers...)
+}
+
+// Names returns the names of the registered analyzers in the order they run
+func (r *AnalyzerRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, len(r.analyzers))
+	for i, a := range r.analyzers {
+		names[i] = a.Name()
+	}
+	return names
+}
+
+// Select returns the named analyzers in the order they run, or every analyzer when
+// names is nil. An empty, non-nil list selects none.
+func (r *AnalyzerRegistry) Select(names []string) ([]Analyzer, error) {
+	if names == nil {
+		return r.All(), nil
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := r.Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown analyzer %q (available: %s)", name, strings.Join(r.Names(), ", "))
+		}
+		wanted[name] = true
+	}
+
+	var selected []Analyzer
+	for _, a := range r.All() {
+		if wanted[a.Name()] {
+			selected = append(selected, a)
+		}
+	}
+	return selected, nil
+}
+
+// checkAnalyzerLimit rejects options that enable more than limit analyzers. Each probe
+// counts as one, since probes send extra requests to the site. A limit of zero allows
+// everything.
+func checkAnalyzerLimit(opts ScanOptions, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	selected, err := analyzers.Select(opts.Analyzers)
+	if err != nil {
+		return err
+	}
+	enabled := len(selected)
+	var probes []string
+	if opts.ProbeSensitivePaths {
+		probes = append(probes, "probe_sensitive_paths")
+	}
+	if opts.ProbeCORS {
+		probes = append(probes, "probe_cors")
+	}
+	if opts.ProbeHTTPSRedirect {
+		probes = append(probes, "probe_https_redirect")
+	}
+	enabled += len(probes)
+	if enabled > limit {
+		counting := ""
+		if len(probes) > 0 {
+			counting = " counting " + strings.Join(probes, " and ")
+		}
+		return fmt.Errorf("%d analyzers enabled%s, more than the limit of %d; choose fewer with analyzers", enabled, counting, limit)
+	}
+	return nil
+}
+
+// analyzers is the registry used by analyzePage
+var analyzers = NewAnalyzerRegistry(defaultAnalyzers()...)
+
+// defaultAnalyzers returns the built-in analyzers. Body-based analyzers come first;
+// the rest only need the headers and redirect chain.
+func defaultAnalyzers() []Analyzer {
+	return []Analyzer{
+		analyzerFunc{"privacy", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.Privacy = privacyAnalyzer.Analyze(page.Body)
+		}},
+		analyzerFunc{"soft_404", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.Soft404, result.Soft404Indicator = detectSoft404(page.StatusCode, page.Body, soft404Patterns)
+		}},
+		analyzerFunc{"keywords", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.Keywords = analyzeKeywords(page.Body, appConfig.KeywordStuffingThreshold, appConfig.ReadingWordsPerMinute)
+		}},
+		analyzerFunc{"accessibility", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.Accessibility = analyzeAccessibility(page.Body)
+		}},
+		analyzerFunc{"performance", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.Performance = analyzePerformance(page.Body)
+		}},
+		analyzerFunc{"seo", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.SEO = analyzeSEO(page.FinalURL, page.Header, page.Body)
+		}},
+		analyzerFunc{"challenge", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.ChallengeDetected, result.ChallengeProvider = detectChallenge(page.StatusCode, page.Header, page.Body, challengeSignatures)
+			// A challenge page stands in for the real site, so its detections can't be trusted
+			if result.ChallengeDetected {
+				result.Warnings = append(result.Warnings, challengeWarning)
+			}
+		}},
+		// Flag HTTPS to HTTP downgrades in the redirect chain
+		analyzerFunc{"redirects", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.SecurityFindings = append(result.SecurityFindings, detectSchemeDowngrades(page.Hops)...)
+		}},
+		// Check Set-Cookie security attributes; insecure session cookies are findings too
+		analyzerFunc{"cookies", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.Cookies = analyzeCookies(page.Header)
+			result.SecurityFindings = append(result.SecurityFindings, cookieFindings(result.Cookies)...)
+		}},
+		analyzerFunc{"cors", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.SecurityFindings = append(result.SecurityFindings, detectCORSMisconfigurations(page.Header)...)
+		}},
+		analyzerFunc{"version_disclosure", func(page *fetchedPage, result *AnalyzeResponse) {
+			result.SecurityFindings = append(result.SecurityFindings, detectVersionDisclosure(page.Header)...)
+		}},
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeAnalyzer records the body it saw and adds a warning to the response
+type fakeAnalyzer struct {
+	sawBody []byte
+}
+
+func (f *fakeAnalyzer) Name() string { return "fake" }
+
+func (f *fakeAnalyzer) Analyze(page *fetchedPage, result *AnalyzeResponse) {
+	f.sawBody = append([]byte(nil), page.Body...)
+	result.Warnings = append(result.Warnings, "fake analyzer ran")
+}
+
+// withAnalyzers replaces the analyzer registry for the duration of the test
+func withAnalyzers(t *testing.T, registry *AnalyzerRegistry) {
+	t.Helper()
+	original := analyzers
+	analyzers = registry
+	t.Cleanup(func() { analyzers = original })
+}
+
+func TestAnalyzePageRunsRegisteredAnalyzers(t *testing.T) {
+	fake := &fakeAnalyzer{}
+	registry := NewAnalyzerRegistry(defaultAnalyzers()...)
+	registry.Register(fake)
+	withAnalyzers(t, registry)
+
+	body := []byte(`<html><head><title>Fake</title></head><body></body></html>`)
+	page := &fetchedPage{Header: http.Header{"Content-Type": {"text/html"}}, Body: body, StatusCode: http.StatusOK}
+	result, apiErr := analyzePage("test-request-id", AnalyzeRequest{URL: "https://example.com"}, ScanOptions{}, page)
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %+v", apiErr)
+	}
+
+	if !bytes.Equal(fake.sawBody, body) {
+		t.Errorf("expected the analyzer to see the page body, got %q", fake.sawBody)
+	}
+	if !containsString(result.Warnings, "fake analyzer ran") {
+		t.Errorf("expected the analyzer's warning in the response, got %v", result.Warnings)
+	}
+	if _, ok := result.Timings["fake"]; !ok {
+		t.Errorf("expected a timing for the registered analyzer, got %v", result.Timings)
+	}
+	if result.Privacy == nil {
+		t.Error("expected the built-in analyzers to keep running")
+	}
+}
+
+func TestAnalyzePageSkipsUnregisteredAnalyzers(t *testing.T) {
+	withAnalyzers(t, NewAnalyzerRegistry())
+
+	page := &fetchedPage{
+		Header:     http.Header{"Server": {"nginx/1.25.3"}, "Set-Cookie": {"session_id=abc"}},
+		Body:       []byte(`<html><head><title>Page Not Found</title></head></html>`),
+		StatusCode: http.StatusOK,
+	}
+	result, apiErr := analyzePage("test-request-id", AnalyzeRequest{URL: "https://example.com"}, ScanOptions{}, page)
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %+v", apiErr)
+	}
+
+	if result.Privacy != nil || result.Soft404 || len(result.Cookies) != 0 || len(result.SecurityFindings) != 0 {
+		t.Errorf("expected no analyzer output with an empty registry, got %+v", result)
+	}
+	if _, ok := result.Timings["technologies"]; !ok {
+		t.Error("expected technology detection to run regardless of the registry")
+	}
+}
+
+func TestAnalyzerRegistry(t *testing.T) {
+	registry := NewAnalyzerRegistry(defaultAnalyzers()...)
+
+	var names []string
+	for _, a := range registry.All() {
+		names = append(names, a.Name())
+	}
+	want := []string{"privacy", "soft_404", "keywords", "accessibility", "performance", "seo", "challenge", "redirects", "cookies", "cors", "version_disclosure"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected analyzers %v in order, got %v", want, names)
+	}
+
+	if _, ok := registry.Lookup("cookies"); !ok {
+		t.Error("expected to look up a built-in analyzer by name")
+	}
+	if _, ok := registry.Lookup("missing"); ok {
+		t.Error("expected lookup of an unknown analyzer to fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a duplicate name to panic")
+		}
+	}()
+	registry.Register(analyzerFunc{name: "cookies", fn: func(*fetchedPage, *AnalyzeResponse) {}})
+}
+
+func TestAnalyzerRegistrySelect(t *testing.T) {
+	registry := NewAnalyzerRegistry(defaultAnalyzers()...)
+
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{"all by default", nil, registry.Names()},
+		{"none", []string{}, nil},
+		{"single", []string{"cookies"}, []string{"cookies"}},
+		{"registration order with duplicates", []string{"cors", "privacy", "cors"}, []string{"privacy", "cors"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := registry.Select(tt.names)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, a := range selected {
+				got = append(got, a.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := registry.Select([]string{"cookies", "spelling"}); err == nil || !strings.Contains(err.Error(), `"spelling"`) {
+		t.Errorf("expected an error naming the unknown analyzer, got %v", err)
+	}
+}
+
+func TestAnalyzeHandlerSelectsAnalyzers(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.URL + "/misconfigured"
+
+	decode := func(rr *httptest.ResponseRecorder) AnalyzeResponse {
+		t.Helper()
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response AnalyzeResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	// A single analyzer leaves the others out of the response
+	single := decode(runPipeline(t, map[string]interface{}{"url": url, "analyzers": []string{"cors"}}))
+	if !hasFinding(single.SecurityFindings, "cors_wildcard_with_credentials") || hasFinding(single.SecurityFindings, "insecure_session_cookie") {
+		t.Errorf("expected only the CORS finding, got %v", single.SecurityFindings)
+	}
+	if single.Privacy != nil || len(single.Cookies) != 0 {
+		t.Errorf("expected unselected analyzers to be omitted, got privacy %+v and cookies %+v", single.Privacy, single.Cookies)
+	}
+	if _, ok := single.Timings["cookies"]; ok {
+		t.Errorf("expected no timing for unselected analyzers, got %v", single.Timings)
+	}
+
+	// Several analyzers via the query parameter
+	req := httptest.NewRequest("POST", "/v1/analyze?analyzers=cookies,privacy", strings.NewReader(`{"url": "`+url+`"}`))
+	rr := httptest.NewRecorder()
+	errorHandlingMiddleware(loggingMiddleware(http.HandlerFunc(analyzeHandler))).ServeHTTP(rr, req)
+	multi := decode(rr)
+	if !hasFinding(multi.SecurityFindings, "insecure_session_cookie") || hasFinding(multi.SecurityFindings, "cors_wildcard_with_credentials") {
+		t.Errorf("expected only the cookie finding, got %v", multi.SecurityFindings)
+	}
+	if multi.Privacy == nil || len(multi.Cookies) != 1 {
+		t.Errorf("expected the privacy report and cookies, got %+v and %+v", multi.Privacy, multi.Cookies)
+	}
+
+	// Technology detection always runs
+	none := decode(runPipeline(t, map[string]interface{}{"url": url, "analyzers": []string{}}))
+	if len(none.SecurityFindings) != 0 || none.Privacy != nil {
+		t.Errorf("expected no analyzer output, got %+v", none)
+	}
+	if _, ok := none.Timings["technologies"]; !ok {
+		t.Errorf("expected technology detection to run, got %v", none.Timings)
+	}
+}
+
+func TestAnalyzeHandlerRejectsUnknownAnalyzers(t *testing.T) {
+	rr := runPipeline(t, map[string]interface{}{"html": inlineTestHTML, "analyzers": []string{"security"}})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `unknown analyzer \"security\"`) {
+		t.Errorf("expected the error to name the unknown analyzer, got %s", rr.Body.String())
+	}
+}
+
+func TestAnalyzeHandlerEnforcesAnalyzerLimit(t *testing.T) {
+	original := appConfig
+	appConfig.MaxAnalyzers = 2
+	defer func() { appConfig = original }()
+
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		expected int
+		detail   string
+	}{
+		{"within the limit", map[string]interface{}{"html": inlineTestHTML, "analyzers": []string{"cookies", "cors"}}, http.StatusOK, ""},
+		{"every analyzer by default", map[string]interface{}{"html": inlineTestHTML}, http.StatusBadRequest, "limit of 2"},
+		{"probing counts as an analyzer", map[string]interface{}{"html": inlineTestHTML, "analyzers": []string{"cookies", "cors"}, "probe_sensitive_paths": true}, http.StatusBadRequest, "counting probe_sensitive_paths"},
+		{"CORS probe counts as an analyzer", map[string]interface{}{"html": inlineTestHTML, "analyzers": []string{"cors"}, "probe_cors": true, "probe_sensitive_paths": true}, http.StatusBadRequest, "counting probe_sensitive_paths and probe_cors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := runPipeline(t, tt.body)
+			if rr.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d: %s", tt.expected, rr.Code, rr.Body.String())
+			}
+			if tt.detail == "" {
+				return
+			}
+			var errResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("failed to decode error: %v", err)
+			}
+			if errResp.Type != ErrorTypeValidation || !strings.Contains(errResp.Details, tt.detail) {
+				t.Errorf("expected a validation error mentioning %q, got %+v", tt.detail, errResp)
+			}
+		})
+	}
+
+	// Zero allows every analyzer
+	appConfig.MaxAnalyzers = 0
+	if rr := runPipeline(t, map[string]interface{}{"html": inlineTestHTML, "probe_sensitive_paths": true}); rr.Code != http.StatusOK {
+		t.Errorf("expected no limit when MAX_ANALYZERS is 0, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Headers sent with every callback delivery
+const (
+	callbackSignatureHeader = "X-Webailyzer-Signature"
+	callbackTimestampHeader = "X-Webailyzer-Timestamp"
+	callbackJobIDHeader     = "X-Webailyzer-Job-Id"
+	callbackStatusHeader    = "X-Webailyzer-Job-Status"
+)
+
+const (
+	// callbackMaxAttempts is the total number of tries to deliver a callback
+	callbackMaxAttempts = 3
+	// callbackAttemptTimeout bounds a single delivery attempt
+	callbackAttemptTimeout = 10 * time.Second
+)
+
+// callbackRetryBaseDelay is the backoff before the first redelivery, doubling after
+// each failure; a variable so tests can shorten it
+var callbackRetryBaseDelay = time.Second
+
+// signCallbackPayload returns the signature header value for a callback body sent at
+// timestamp: the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with CALLBACK_SECRET,
+// prefixed with "sha256=". Signing the timestamp lets receivers reject replays.
+func signCallbackPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// callbackHTTPClient returns the client for callback deliveries. It shares the fetch
+// transport but never follows redirects, which would send the signed result to a URL
+// that was never validated.
+func callbackHTTPClient() *http.Client {
+	client := *createHTTPClient()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client
+}
+
+// deliverCallback POSTs a finished job to its callback URL: the AnalyzeResponse when
+// the analysis succeeded, otherwise the error body. Network errors, 429 and 5xx
+// responses are retried with exponential backoff; redirects count as failures.
+func deliverCallback(requestID, jobID, callbackURL, secret string, result *AnalyzeResponse, apiErr *APIError) {
+	status := JobDone
+	var payload interface{} = result
+	if apiErr != nil {
+		status = JobFailed
+		payload = ErrorResponse{
+			Error:     apiErr.Message,
+			Type:      apiErr.Type,
+			Details:   apiErr.Details,
+			RequestID: apiErr.RequestID,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+
+	fields := logrus.Fields{
+		"request_id":   requestID,
+		"job_id":       jobID,
+		"callback_url": callbackURL,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		fields["error"] = err
+		logger.WithFields(fields).Error("Failed to encode callback payload")
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := postCallback(callbackURL, secret, body, jobID, status)
+		if err == nil {
+			logger.WithFields(fields).Info("Callback delivered")
+			return
+		}
+
+		fields["attempt"] = attempt
+		fields["error"] = err
+		if !retryable || attempt >= callbackMaxAttempts {
+			logger.WithFields(fields).Error("Callback delivery failed")
+			return
+		}
+		logger.WithFields(fields).Warn("Callback delivery failed, retrying")
+		time.Sleep(callbackRetryBaseDelay << (attempt - 1))
+	}
+}
+
+// postCallback makes one delivery attempt, signed with the time it is sent, reporting
+// whether a failure is worth retrying
+func postCallback(callbackURL, secret string, body []byte, jobID string, status JobStatus) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callbackAttemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", defaultUserAgent)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(callbackTimestampHeader, timestamp)
+	req.Header.Set(callbackSignatureHeader, signCallbackPayload(secret, timestamp, body))
+	req.Header.Set(callbackJobIDHeader, jobID)
+	req.Header.Set(callbackStatusHeader, string(status))
+
+	resp, err := callbackHTTPClient().Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("callback returned status %d", resp.StatusCode)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testCallbackSecret = "test-callback-secret"
+
+// callbackDelivery is a request received by the test callback receiver
+type callbackDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// newCallbackReceiver records deliveries, answering the first failures with 503
+func newCallbackReceiver(t *testing.T, failures int) (*httptest.Server, chan callbackDelivery) {
+	t.Helper()
+
+	var mu sync.Mutex
+	attempts := 0
+	deliveries := make(chan callbackDelivery, callbackMaxAttempts)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- callbackDelivery{header: r.Header.Clone(), body: body}
+
+		mu.Lock()
+		attempts++
+		fail := attempts <= failures
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, deliveries
+}
+
+// withCallbackSecret enables callbacks and shortens the redelivery backoff
+func withCallbackSecret(t *testing.T) {
+	t.Helper()
+	originalConfig, originalDelay := appConfig, callbackRetryBaseDelay
+	appConfig.CallbackSecret = testCallbackSecret
+	callbackRetryBaseDelay = time.Millisecond
+	t.Cleanup(func() {
+		appConfig = originalConfig
+		callbackRetryBaseDelay = originalDelay
+	})
+}
+
+func receiveDelivery(t *testing.T, deliveries chan callbackDelivery) callbackDelivery {
+	t.Helper()
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a callback delivery")
+		return callbackDelivery{}
+	}
+}
+
+// verifySignature checks the signature the way a receiver would, independently of
+// signCallbackPayload, rejecting timestamps outside the documented five minute window
+func verifySignature(header http.Header, body []byte, secret string) bool {
+	timestamp := header.Get(callbackTimestampHeader)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > 5*time.Minute || age < -5*time.Minute {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(header.Get(callbackSignatureHeader)), []byte(expected))
+}
+
+func TestAnalyzeWithCallbackDeliversSignedResult(t *testing.T) {
+	withCallbackSecret(t)
+	withJobQueue(t, 10, 1)
+	receiver, deliveries := newCallbackReceiver(t, 1)
+
+	rr := runPipeline(t, map[string]interface{}{
+		"html":         inlineTestHTML,
+		"callback_url": receiver.URL + "/hooks/analysis",
+	})
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var job Job
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	if rr.Header().Get("Location") != "/v1/jobs/"+job.ID {
+		t.Errorf("expected Location /v1/jobs/%s, got %q", job.ID, rr.Header().Get("Location"))
+	}
+
+	// The first attempt is refused and redelivered
+	first := receiveDelivery(t, deliveries)
+	delivery := receiveDelivery(t, deliveries)
+	if string(first.body) != string(delivery.body) {
+		t.Error("expected the redelivery to carry the same payload")
+	}
+
+	if !verifySignature(delivery.header, delivery.body, testCallbackSecret) {
+		t.Errorf("signature %q does not verify", delivery.header.Get(callbackSignatureHeader))
+	}
+	if verifySignature(delivery.header, delivery.body, "wrong-secret") {
+		t.Error("signature should not verify with a different secret")
+	}
+	replayed := delivery.header.Clone()
+	replayed.Set(callbackTimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	if verifySignature(replayed, delivery.body, testCallbackSecret) {
+		t.Error("signature should not verify with a different timestamp")
+	}
+	if delivery.header.Get(callbackJobIDHeader) != job.ID || delivery.header.Get(callbackStatusHeader) != string(JobDone) {
+		t.Errorf("unexpected job headers: id %q, status %q", delivery.header.Get(callbackJobIDHeader), delivery.header.Get(callbackStatusHeader))
+	}
+
+	var result AnalyzeResponse
+	if err := json.Unmarshal(delivery.body, &result); err != nil {
+		t.Fatalf("callback body is not an analysis response: %v", err)
+	}
+	if len(result.Detected) == 0 {
+		t.Errorf("expected detections in the delivered result, got %s", delivery.body)
+	}
+}
+
+func TestCallbackDeliversFailures(t *testing.T) {
+	withCallbackSecret(t)
+	withJobQueue(t, 10, 1)
+	receiver, deliveries := newCallbackReceiver(t, 0)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	target := closed.URL
+	closed.Close()
+
+	rr := runPipeline(t, map[string]interface{}{"url": target, "callback_url": receiver.URL})
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	delivery := receiveDelivery(t, deliveries)
+	if delivery.header.Get(callbackStatusHeader) != string(JobFailed) {
+		t.Errorf("expected status header %q, got %q", JobFailed, delivery.header.Get(callbackStatusHeader))
+	}
+	if !verifySignature(delivery.header, delivery.body, testCallbackSecret) {
+		t.Error("expected failure deliveries to be signed too")
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(delivery.body, &errResp); err != nil || errResp.Type != ErrorTypeNetwork {
+		t.Errorf("expected a network error body, got %s", delivery.body)
+	}
+}
+
+func TestAnalyzeRejectsInvalidCallbacks(t *testing.T) {
+	withJobQueue(t, 10, 1)
+
+	// Callbacks are refused while there is no secret to sign them with
+	rr := runPipeline(t, map[string]interface{}{"html": inlineTestHTML, "callback_url": "https://hooks.example.com"})
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "CALLBACK_SECRET") {
+		t.Errorf("expected a 400 naming CALLBACK_SECRET, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	withCallbackSecret(t)
+	for _, callbackURL := range []string{"ftp://hooks.example.com", "file:///etc/passwd", "https://"} {
+		rr := runPipeline(t, map[string]interface{}{"html": inlineTestHTML, "callback_url": callbackURL})
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "Invalid callback URL") {
+			t.Errorf("expected a 400 for callback %q, got %d: %s", callbackURL, rr.Code, rr.Body.String())
+		}
+	}
+	if n := jobs.store.size(); n != 0 {
+		t.Errorf("expected rejected callbacks not to create jobs, got %d", n)
+	}
+}
+
+func TestSignCallbackPayload(t *testing.T) {
+	// RFC 4231 test case 2, with the timestamp prefixed to the message
+	got := signCallbackPayload("Jefe", "1700000000", []byte("what do ya want for nothing?"))
+	want := "sha256=1cdd0650c8be1cb0974b1788d458b1e781206cfef59b85faafc582d2e182c57e"
+	if got != want {
+		t.Errorf("signCallbackPayload() = %s, want %s", got, want)
+	}
+}
+
+func TestPostCallbackDoesNotFollowRedirects(t *testing.T) {
+	var followed int32
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&followed, 1)
+	}))
+	defer elsewhere.Close()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, elsewhere.URL, http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	retryable, err := postCallback(receiver.URL, testCallbackSecret, []byte(`{}`), "job", JobDone)
+	if err == nil || retryable {
+		t.Errorf("expected a redirect to fail without retrying, got retryable=%v, err=%v", retryable, err)
+	}
+	if n := atomic.LoadInt32(&followed); n != 0 {
+		t.Errorf("expected the signed payload not to follow the redirect, got %d requests", n)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// challengeWarning is added to responses when the analyzed page looks like a bot challenge
+const challengeWarning = "The page looks like a bot challenge or interstitial rather than the site's content; detections may be incomplete or misleading"
+
+// challengeSignature identifies a provider's bot-challenge or interstitial page
+type challengeSignature struct {
+	Provider string
+	// StatusCodes, when set, limits the signature to responses with one of these
+	// statuses, for providers whose markers also appear on the pages they let through
+	StatusCodes []int
+	// Header, when set, matches a response header; HeaderValue narrows it to values containing that substring
+	Header      string
+	HeaderValue string
+	// BodyMarkers are case-insensitive substrings that only appear on the provider's challenge pages
+	BodyMarkers []string
+}
+
+// challengeSignatures lists the interstitials served by common CDNs and bot-management vendors.
+// Scripts that providers inject into every protected page, such as Cloudflare's
+// /cdn-cgi/challenge-platform/ loader or Imperva's _Incapsula_Resource, are not markers.
+var challengeSignatures = []challengeSignature{
+	{
+		Provider:    "Cloudflare",
+		Header:      "Cf-Mitigated",
+		HeaderValue: "challenge",
+		BodyMarkers: []string{"window._cf_chl_opt", "cf-browser-verification"},
+	},
+	{
+		Provider:    "Sucuri",
+		BodyMarkers: []string{"sucuri website firewall", "sucuri_cloudproxy_js"},
+	},
+	{
+		Provider:    "DDoS-Guard",
+		BodyMarkers: []string{"ddos-guard.net/js/", "<title>ddos-guard</title>"},
+	},
+	{
+		Provider:    "Imperva",
+		BodyMarkers: []string{"incapsula incident id", "_incapsula_resource?cwudnsai="},
+	},
+	{
+		// DataDome sets X-DataDome on every response it passes, so only its 403 captcha counts
+		Provider:    "DataDome",
+		StatusCodes: []int{http.StatusForbidden},
+		BodyMarkers: []string{"geo.captcha-delivery.com", "ct.captcha-delivery.com"},
+	},
+	{
+		Provider:    "PerimeterX",
+		StatusCodes: []int{http.StatusForbidden},
+		BodyMarkers: []string{"captcha.px-cdn.net", "px-captcha"},
+	},
+	{
+		// AWS WAF answers challenges with 202 and CAPTCHAs with 405; its token script
+		// also loads on pages that passed
+		Provider:    "AWS WAF",
+		StatusCodes: []int{http.StatusAccepted, http.StatusMethodNotAllowed},
+		BodyMarkers: []string{"awswafintegration", ".token.awswaf.com/"},
+	},
+}
+
+// matchesStatus reports whether the signature applies to a response with this status
+func (sig challengeSignature) matchesStatus(statusCode int) bool {
+	if len(sig.StatusCodes) == 0 {
+		return true
+	}
+	for _, code := range sig.StatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// detectChallenge reports whether a response looks like a bot challenge, returning the
+// suspected provider. A matching header wins over body markers.
+func detectChallenge(statusCode int, header http.Header, body []byte, signatures []challengeSignature) (bool, string) {
+	for _, sig := range signatures {
+		if sig.Header == "" || !sig.matchesStatus(statusCode) {
+			continue
+		}
+		if value := header.Get(sig.Header); value != "" &&
+			strings.Contains(strings.ToLower(value), strings.ToLower(sig.HeaderValue)) {
+			return true, sig.Provider
+		}
+	}
+
+	lower := bytes.ToLower(body)
+	for _, sig := range signatures {
+		if !sig.matchesStatus(statusCode) {
+			continue
+		}
+		for _, marker := range sig.BodyMarkers {
+			if bytes.Contains(lower, []byte(marker)) {
+				return true, sig.Provider
+			}
+		}
+	}
+	return false, ""
+}
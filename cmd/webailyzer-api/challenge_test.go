@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const cloudflareChallengeHTML = `<!DOCTYPE html><html lang="en-US"><head><title>Just a moment...</title></head>
+<body><div class="main-wrapper"><h1>Checking your browser before accessing example.com</h1></div>
+<script>(function(){window._cf_chl_opt={cvId: '3',cZone: 'example.com',cType: 'managed'};
+var a = document.createElement('script');a.src = '/cdn-cgi/challenge-platform/h/g/orchestrate/chl_page/v1';
+document.getElementsByTagName('head')[0].appendChild(a);}());</script></body></html>`
+
+func TestDetectChallenge(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		header     http.Header
+		body       string
+		provider   string
+	}{
+		{"cloudflare body", http.StatusOK, http.Header{}, cloudflareChallengeHTML, "Cloudflare"},
+		{"cloudflare header", http.StatusForbidden, http.Header{"Cf-Mitigated": {"challenge"}}, `<html></html>`, "Cloudflare"},
+		{"cloudflare loader on a normal page", http.StatusOK, http.Header{"Server": {"cloudflare"}}, `<html><body>Welcome<script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script></body></html>`, ""},
+		{"datadome header on a normal page", http.StatusOK, http.Header{"X-Datadome": {"protected"}}, `<html></html>`, ""},
+		{"datadome captcha", http.StatusForbidden, http.Header{"X-Datadome": {"protected"}}, `<html><script src="https://ct.captcha-delivery.com/c.js"></script></html>`, "DataDome"},
+		{"perimeterx app id on a normal page", http.StatusOK, http.Header{}, `<html><script>window._pxAppId = 'PXabc123';</script><body>Shop</body></html>`, ""},
+		{"perimeterx block page", http.StatusForbidden, http.Header{}, `<html><body><div id="px-captcha"></div><script src="https://captcha.px-cdn.net/PXabc123/captcha.js"></script></body></html>`, "PerimeterX"},
+		{"aws waf integration on a normal page", http.StatusOK, http.Header{}, `<html><script src="https://abc.edge.sdk.awswaf.com/abc/def/challenge.js"></script><script>AwsWafIntegration.getToken()</script></html>`, ""},
+		{"aws waf captcha", http.StatusMethodNotAllowed, http.Header{}, `<html><script>AwsWafIntegration.checkForceRefresh()</script></html>`, "AWS WAF"},
+		{"imperva script on a normal page", http.StatusOK, http.Header{}, `<html><script src="/_Incapsula_Resource?SWJIYLWA=719d34d31c8e3a6e6fffd425f7e032f3"></script></html>`, ""},
+		{"imperva incident page", http.StatusOK, http.Header{}, `<html><body>Request unsuccessful. Incapsula incident ID: 123000450123456789-12345678901234567</body></html>`, "Imperva"},
+		{"sucuri body", http.StatusOK, http.Header{}, `<html><title>Sucuri WebSite Firewall - Access Denied</title></html>`, "Sucuri"},
+		{"normal page", http.StatusOK, http.Header{"Server": {"cloudflare"}}, `<html><title>Just a moment of your time</title><body>Welcome</body></html>`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detected, provider := detectChallenge(tt.statusCode, tt.header, []byte(tt.body), challengeSignatures)
+			if detected != (tt.provider != "") || provider != tt.provider {
+				t.Errorf("expected provider %q, got detected=%v provider %q", tt.provider, detected, provider)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerFlagsChallengePage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.Header().Set("Server", "cloudflare")
+		w.Write([]byte(cloudflareChallengeHTML))
+	}))
+	defer server.Close()
+
+	rr := runPipeline(t, map[string]interface{}{"url": server.URL})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !response.ChallengeDetected || response.ChallengeProvider != "Cloudflare" {
+		t.Errorf("expected a Cloudflare challenge, got %v %q", response.ChallengeDetected, response.ChallengeProvider)
+	}
+	if len(response.Warnings) != 1 || response.Warnings[0] != challengeWarning {
+		t.Errorf("expected the unreliable results warning, got %v", response.Warnings)
+	}
+
+	inline := postAnalyze(t, map[string]string{"html": inlineTestHTML})
+	var normal AnalyzeResponse
+	if err := json.Unmarshal(inline.Body.Bytes(), &normal); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if normal.ChallengeDetected || len(normal.Warnings) != 0 {
+		t.Errorf("expected no challenge on a normal page, got %v %v", normal.ChallengeDetected, normal.Warnings)
+	}
+}
+
+func TestAnalyzeHandlerAnalyzesForbiddenChallenge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("X-DataDome", "protected")
+		if r.URL.Path == "/denied" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<html><body>Forbidden</body></html>`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<html><script>var dd={'cid':'abc','hsh':'def','t':'fe','host':'geo.captcha-delivery.com'}</script>
+<script src="https://ct.captcha-delivery.com/c.js"></script></html>`))
+	}))
+	defer server.Close()
+
+	rr := runPipeline(t, map[string]interface{}{"url": server.URL + "/captcha"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected the challenge to be analyzed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !response.ChallengeDetected || response.ChallengeProvider != "DataDome" {
+		t.Errorf("expected a DataDome challenge, got %v %q", response.ChallengeDetected, response.ChallengeProvider)
+	}
+
+	// A 403 that is not a challenge is still the site's error
+	if rr := runPipeline(t, map[string]interface{}{"url": server.URL + "/denied"}); rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a plain denial, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
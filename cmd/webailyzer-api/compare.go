@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CompareRequest selects two URLs to analyze side by side, such as production and staging.
+// The scan options apply to both fetches.
+type CompareRequest struct {
+	URLA           string   `json:"url_a"`
+	URLB           string   `json:"url_b"`
+	Profile        string   `json:"profile,omitempty"`
+	UserAgent      string   `json:"user_agent,omitempty"`
+	TimeoutMs      int      `json:"timeout_ms,omitempty"`
+	IncludeHeaders *bool    `json:"include_headers,omitempty"`
+	Analyzers      []string `json:"analyzers,omitempty"`
+	// The probe options probe each site as for a single analysis
+	ProbeSensitivePaths *bool `json:"probe_sensitive_paths,omitempty"`
+	ProbeCORS           *bool `json:"probe_cors,omitempty"`
+	ProbeHTTPSRedirect  *bool `json:"probe_https_redirect,omitempty"`
+}
+
+// CompareResponse holds both analyses and what changed from A to B
+type CompareResponse struct {
+	A    *AnalyzeResponse `json:"a"`
+	B    *AnalyzeResponse `json:"b"`
+	Diff AnalysisDiff     `json:"diff"`
+}
+
+// AnalysisDiff describes the changes from analysis A to analysis B
+type AnalysisDiff struct {
+	TechnologiesAdded   []string          `json:"technologies_added"`
+	TechnologiesRemoved []string          `json:"technologies_removed"`
+	VersionChanges      []VersionChange   `json:"version_changes,omitempty"`
+	FindingsAdded       []SecurityFinding `json:"findings_added,omitempty"`
+	FindingsRemoved     []SecurityFinding `json:"findings_removed,omitempty"`
+	TrackersAdded       []string          `json:"trackers_added,omitempty"`
+	TrackersRemoved     []string          `json:"trackers_removed,omitempty"`
+	StatusCodeChanged   bool              `json:"status_code_changed"`
+}
+
+// VersionChange is a technology detected on both sides with different versions
+type VersionChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// compareHandler analyzes two URLs concurrently and returns both results with their diff
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := ""
+	if id := r.Context().Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	var cmp CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&cmp); err != nil {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid JSON format",
+			Details:    "Request body must be valid JSON",
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	// Both URLs go through the same validation as a single analysis
+	for _, side := range []struct{ field, url string }{{"url_a", cmp.URLA}, {"url_b", cmp.URLB}} {
+		if err := validateURL(side.url); err != nil {
+			sendErrorResponse(w, APIError{
+				Type:       ErrorTypeValidation,
+				Message:    "Invalid URL",
+				Details:    fmt.Sprintf("%s: %v", side.field, err),
+				StatusCode: http.StatusBadRequest,
+				RequestID:  requestID,
+			})
+			return
+		}
+	}
+
+	reqA := AnalyzeRequest{
+		URL:                 cmp.URLA,
+		Profile:             cmp.Profile,
+		UserAgent:           cmp.UserAgent,
+		TimeoutMs:           cmp.TimeoutMs,
+		IncludeHeaders:      cmp.IncludeHeaders,
+		Analyzers:           cmp.Analyzers,
+		ProbeSensitivePaths: cmp.ProbeSensitivePaths,
+		ProbeCORS:           cmp.ProbeCORS,
+		ProbeHTTPSRedirect:  cmp.ProbeHTTPSRedirect,
+	}
+	reqB := reqA
+	reqB.URL = cmp.URLB
+
+	opts, err := resolveScanOptions(reqA)
+	if err != nil {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid scan options",
+			Details:    err.Error(),
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"url_a":      cmp.URLA,
+		"url_b":      cmp.URLB,
+		"profile":    opts.Profile,
+	}).Info("Starting URL comparison")
+
+	// Both fetches share the one time budget
+	ctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		resultA    *AnalyzeResponse
+		resultB    *AnalyzeResponse
+		errA, errB *APIError
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		resultA, errA = runAnalysis(ctx, requestID, reqA, opts, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		resultB, errB = runAnalysis(ctx, requestID, reqB, opts, nil)
+	}()
+	wg.Wait()
+
+	if errA != nil {
+		errA.Details = "url_a: " + errA.Details
+		sendErrorResponse(w, *errA)
+		return
+	}
+	if errB != nil {
+		errB.Details = "url_b: " + errB.Details
+		sendErrorResponse(w, *errB)
+		return
+	}
+
+	response := CompareResponse{
+		A:    resultA,
+		B:    resultB,
+		Diff: diffAnalyses(resultA, resultB),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to encode comparison response")
+	}
+}
+
+// diffAnalyses reports what changed from analysis a to analysis b
+func diffAnalyses(a, b *AnalyzeResponse) AnalysisDiff {
+	diff := AnalysisDiff{
+		TechnologiesAdded:   []string{},
+		TechnologiesRemoved: []string{},
+		StatusCodeChanged:   a.StatusCode != b.StatusCode,
+	}
+
+	// Detected keys carry the version after a colon, e.g. "WordPress:6.4.2"
+	techA, techB := technologyVersions(a.Detected), technologyVersions(b.Detected)
+	for name, versionB := range techB {
+		versionA, ok := techA[name]
+		if !ok {
+			diff.TechnologiesAdded = append(diff.TechnologiesAdded, name)
+		} else if versionA != versionB {
+			diff.VersionChanges = append(diff.VersionChanges, VersionChange{Name: name, From: versionA, To: versionB})
+		}
+	}
+	for name := range techA {
+		if _, ok := techB[name]; !ok {
+			diff.TechnologiesRemoved = append(diff.TechnologiesRemoved, name)
+		}
+	}
+	sort.Strings(diff.TechnologiesAdded)
+	sort.Strings(diff.TechnologiesRemoved)
+	sort.Slice(diff.VersionChanges, func(i, j int) bool {
+		return diff.VersionChanges[i].Name < diff.VersionChanges[j].Name
+	})
+
+	diff.FindingsAdded = subtractFindings(b.SecurityFindings, a.SecurityFindings)
+	diff.FindingsRemoved = subtractFindings(a.SecurityFindings, b.SecurityFindings)
+
+	trackersA, trackersB := reportTrackerNames(a.Privacy), reportTrackerNames(b.Privacy)
+	diff.TrackersAdded = subtractStrings(trackersB, trackersA)
+	diff.TrackersRemoved = subtractStrings(trackersA, trackersB)
+
+	return diff
+}
+
+// technologyVersions maps each detected technology name to its version, or "" when unversioned
+func technologyVersions(detected map[string]interface{}) map[string]string {
+	versions := make(map[string]string, len(detected))
+	for key := range detected {
+		name, version, _ := strings.Cut(key, ":")
+		versions[name] = version
+	}
+	return versions
+}
+
+// findingKey identifies a finding by type, severity and subject. Messages name hosts
+// and header values, so the same issue on both sides would otherwise never match.
+type findingKey struct {
+	Type, Severity, Subject string
+}
+
+// subtractFindings returns the findings in from that have no matching finding in other
+func subtractFindings(from, other []SecurityFinding) []SecurityFinding {
+	seen := make(map[findingKey]bool, len(other))
+	for _, f := range other {
+		seen[findingKey{f.Type, f.Severity, f.Subject}] = true
+	}
+	var result []SecurityFinding
+	for _, f := range from {
+		if !seen[findingKey{f.Type, f.Severity, f.Subject}] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// reportTrackerNames returns the sorted names of all trackers in the report
+func reportTrackerNames(report *PrivacyReport) []string {
+	if report == nil {
+		return nil
+	}
+	var names []string
+	for _, trackers := range report.Trackers {
+		for _, tracker := range trackers {
+			names = append(names, tracker.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// subtractStrings returns the values in from that are not in other, preserving order
+func subtractStrings(from, other []string) []string {
+	seen := make(map[string]bool, len(other))
+	for _, s := range other {
+		seen[s] = true
+	}
+	var result []string
+	for _, s := range from {
+		if !seen[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func postCompare(t *testing.T, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	requestBody, _ := json.Marshal(body)
+	req, err := http.NewRequest("POST", "/v1/analyze/compare", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	compareHandler(rr, req)
+	return rr
+}
+
+func TestCompareHandler(t *testing.T) {
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("X-Powered-By", "PHP/8.1")
+		w.Header().Set("Server", "nginx/1.24.0")
+		w.Header().Add("Set-Cookie", "PHPSESSID=abc; Path=/")
+		w.Write([]byte(`<html><head><meta name="generator" content="WordPress 6.4.2">
+<script src="https://www.google-analytics.com/analytics.js"></script></head><body></body></html>`))
+	}))
+	defer prod.Close()
+
+	staging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Write([]byte(`<html><head><script src="/static/js/react.production.min.js"></script></head>
+<body><div id="root" data-reactroot=""></div></body></html>`))
+	}))
+	defer staging.Close()
+
+	rr := postCompare(t, map[string]string{"url_a": prod.URL, "url_b": staging.URL})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response CompareResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.A == nil || response.A.URL != prod.URL || response.B == nil || response.B.URL != staging.URL {
+		t.Fatalf("expected both analyses in request order, got %+v", response)
+	}
+
+	diff := response.Diff
+	if !reflect.DeepEqual(diff.TechnologiesAdded, []string{"React"}) {
+		t.Errorf("expected React to be added, got %v", diff.TechnologiesAdded)
+	}
+	for _, tech := range []string{"WordPress", "PHP"} {
+		if !containsString(diff.TechnologiesRemoved, tech) {
+			t.Errorf("expected %s to be removed, got %v", tech, diff.TechnologiesRemoved)
+		}
+	}
+	want := []VersionChange{{Name: "Nginx", From: "1.24.0", To: "1.25.3"}}
+	if !reflect.DeepEqual(diff.VersionChanges, want) {
+		t.Errorf("expected version changes %v, got %v", want, diff.VersionChanges)
+	}
+	// Both servers disclose their nginx version, so only the X-Powered-By disclosure goes
+	if len(diff.FindingsRemoved) != 2 || !hasFinding(diff.FindingsRemoved, "insecure_session_cookie") {
+		t.Errorf("expected the session cookie and the X-Powered-By disclosure to be removed, got %v", diff.FindingsRemoved)
+	}
+	for _, f := range diff.FindingsRemoved {
+		if f.Type == "information_disclosure" && f.Subject != "X-Powered-By" {
+			t.Errorf("expected only the X-Powered-By disclosure to be removed, got %v", diff.FindingsRemoved)
+		}
+	}
+	if len(diff.FindingsAdded) != 0 {
+		t.Errorf("expected no findings to be added, got %v", diff.FindingsAdded)
+	}
+	if !reflect.DeepEqual(diff.TrackersRemoved, []string{"Google Analytics"}) || len(diff.TrackersAdded) != 0 {
+		t.Errorf("expected Google Analytics to be removed, got +%v -%v", diff.TrackersAdded, diff.TrackersRemoved)
+	}
+	if diff.StatusCodeChanged {
+		t.Error("both pages returned 200")
+	}
+}
+
+func TestCompareHandlerRunsProfileProbes(t *testing.T) {
+	newSite := func(reflect bool) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if origin := r.Header.Get("Origin"); origin != "" && reflect {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Site</title></head><body></body></html>`))
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	reflecting, strict := newSite(true), newSite(false)
+
+	// security-deep enables probe_cors, which must run for compare as for /v1/analyze
+	rr := postCompare(t, map[string]string{"url_a": reflecting.URL, "url_b": strict.URL, "profile": "security-deep"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response CompareResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !hasFinding(response.A.SecurityFindings, "cors_origin_reflected") {
+		t.Errorf("expected the CORS probe to run for url_a, got %+v", response.A.SecurityFindings)
+	}
+	if _, ok := response.B.Timings["cors_probe"]; !ok {
+		t.Errorf("expected the CORS probe to run for url_b, got timings %v", response.B.Timings)
+	}
+	if !hasFinding(response.Diff.FindingsRemoved, "cors_origin_reflected") {
+		t.Errorf("expected the reflected origin to be removed from A to B, got %+v", response.Diff.FindingsRemoved)
+	}
+}
+
+func TestDiffAnalysesMatchesFindingsBySubject(t *testing.T) {
+	a := &AnalyzeResponse{SecurityFindings: []SecurityFinding{
+		{Type: "information_disclosure", Severity: "low", Subject: "Server", Message: "The Server header discloses nginx 1.24.0"},
+		{Type: "insecure_session_cookie", Severity: "high", Subject: "PHPSESSID", Message: `Session cookie "PHPSESSID" is missing Secure`},
+		{Type: "https_downgrade", Severity: "high", Subject: "/login", Message: "Redirect from https://example.com/ downgrades to insecure http://example.com/login"},
+	}}
+	b := &AnalyzeResponse{SecurityFindings: []SecurityFinding{
+		{Type: "information_disclosure", Severity: "low", Subject: "Server", Message: "The Server header discloses nginx 1.25.3"},
+		{Type: "insecure_session_cookie", Severity: "high", Subject: "PHPSESSID", Message: `Session cookie "PHPSESSID" is missing Secure and HttpOnly`},
+		{Type: "https_downgrade", Severity: "high", Subject: "/login", Message: "Redirect from https://staging.example.com/ downgrades to insecure http://staging.example.com/login"},
+		{Type: "insecure_session_cookie", Severity: "high", Subject: "token", Message: `Session cookie "token" is missing HttpOnly`},
+	}}
+
+	diff := diffAnalyses(a, b)
+	if len(diff.FindingsRemoved) != 0 {
+		t.Errorf("expected findings reworded on the other side not to be removed, got %v", diff.FindingsRemoved)
+	}
+	if len(diff.FindingsAdded) != 1 || diff.FindingsAdded[0].Subject != "token" {
+		t.Errorf("expected only the finding for the token cookie to be added, got %v", diff.FindingsAdded)
+	}
+}
+
+func TestCompareHandlerErrors(t *testing.T) {
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html></html>`))
+	}))
+	defer ok.Close()
+
+	tests := []struct {
+		name    string
+		body    map[string]string
+		status  int
+		details string
+	}{
+		{"missing url_b", map[string]string{"url_a": ok.URL}, http.StatusBadRequest, "url_b: URL is required"},
+		{"invalid scheme", map[string]string{"url_a": "file:///etc/passwd", "url_b": ok.URL}, http.StatusBadRequest, "url_a: only HTTP and HTTPS"},
+		{"unknown profile", map[string]string{"url_a": ok.URL, "url_b": ok.URL, "profile": "nope"}, http.StatusBadRequest, "unknown scan profile"},
+		{"fetch failure", map[string]string{"url_a": ok.URL, "url_b": missing.URL}, http.StatusNotFound, "url_b: The URL returned status code 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postCompare(t, tt.body)
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+
+			var response ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if !strings.Contains(response.Details, tt.details) {
+				t.Errorf("expected details containing %q, got %q", tt.details, response.Details)
+			}
+		})
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
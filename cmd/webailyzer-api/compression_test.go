@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveCompressed(t *testing.T, acceptEncoding string, handler http.HandlerFunc) (*httptest.ResponseRecorder, *responseWriter) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/v1/categories", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rr := httptest.NewRecorder()
+	// Wrap like loggingMiddleware does, to check the status still reaches it
+	wrapped := &responseWriter{ResponseWriter: rr, statusCode: http.StatusOK}
+	compressionMiddleware(handler).ServeHTTP(wrapped, req)
+	return rr, wrapped
+}
+
+func TestCompressionMiddlewareGzipsLargeBodies(t *testing.T) {
+	body := `{"categories":[` + strings.Repeat(`{"id":1,"name":"CMS","priority":1},`, 100) + `{}]}`
+
+	rr, wrapped := serveCompressed(t, "br, gzip;q=0.8", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "999")
+		w.WriteHeader(http.StatusCreated)
+		// Write in small pieces so the body crosses gzipMinSize mid-stream
+		for i := 0; i < len(body); i += 100 {
+			end := i + 100
+			if end > len(body) {
+				end = len(body)
+			}
+			w.Write([]byte(body[i:end]))
+		}
+	})
+
+	if rr.Code != http.StatusCreated || wrapped.statusCode != http.StatusCreated {
+		t.Errorf("expected status 201 downstream and in the status wrapper, got %d and %d", rr.Code, wrapped.statusCode)
+	}
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got headers %v", rr.Header())
+	}
+	if rr.Header().Get("Content-Length") != "" {
+		t.Error("expected Content-Length to be removed from a compressed response")
+	}
+	if rr.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected Vary: Accept-Encoding, got %q", rr.Header().Get("Vary"))
+	}
+
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("response is not valid gzip: %v", err)
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress response: %v", err)
+	}
+	if string(decompressed) != body {
+		t.Errorf("decompressed body does not match: got %d bytes, want %d", len(decompressed), len(body))
+	}
+}
+
+func TestCompressionMiddlewareSkips(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), 4*gzipMinSize)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		encoding       string
+		body           []byte
+	}{
+		{"no accept-encoding", "", "application/json", "", large},
+		{"gzip refused", "gzip;q=0, deflate", "application/json", "", large},
+		{"tiny body", "gzip", "application/json", "", []byte(`{"status":"ok"}`)},
+		{"already encoded", "gzip", "application/json", "br", large},
+		{"compressed media", "gzip", "image/png", "", large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, _ := serveCompressed(t, tt.acceptEncoding, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			})
+
+			if got := rr.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+			if !bytes.Equal(rr.Body.Bytes(), tt.body) {
+				t.Errorf("expected the body to pass through unchanged, got %d bytes", rr.Body.Len())
+			}
+		})
+	}
+}
+
+func TestCompressionMiddlewareBodylessResponse(t *testing.T) {
+	rr, wrapped := serveCompressed(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if rr.Code != http.StatusNoContent || wrapped.statusCode != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d and %d", rr.Code, wrapped.statusCode)
+	}
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.Len() != 0 {
+		t.Errorf("expected an empty, unencoded response, got %q with %d bytes", rr.Header().Get("Content-Encoding"), rr.Body.Len())
+	}
+}
+
+func TestCompressionMiddlewareHandlerOutlivesTimeout(t *testing.T) {
+	saved := defaultRequestTimeout
+	defaultRequestTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { defaultRequestTimeout = saved })
+
+	chunk := bytes.Repeat([]byte("late "), 1000)
+	finished := make(chan struct{})
+	handler := timeoutMiddleware(compressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(finished)
+		// Start a gzipped response, then keep writing past the deadline
+		w.Write(chunk)
+		<-r.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		for i := 0; i < 50; i++ {
+			w.Write(chunk)
+		}
+	})))
+
+	req := httptest.NewRequest("GET", "/v1/categories", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	written := rr.Body.Len()
+
+	// Another response takes pooled gzip writers while the late handler is writing
+	other, _ := serveCompressed(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 50; i++ {
+			w.Write(chunk)
+		}
+	})
+	<-finished
+
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected the handler's gzipped response to have started, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	if rr.Body.Len() != written {
+		t.Errorf("expected no writes after the timeout, body grew from %d to %d bytes", written, rr.Body.Len())
+	}
+
+	zr, err := gzip.NewReader(other.Body)
+	if err != nil {
+		t.Fatalf("expected a gzip body for the other response: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil || len(body) != 50*len(chunk) {
+		t.Errorf("expected the other response intact, got %d bytes (err %v)", len(body), err)
+	}
+}
+
+func TestTimeoutMiddlewareDropsLateWrites(t *testing.T) {
+	saved := defaultRequestTimeout
+	defaultRequestTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { defaultRequestTimeout = saved })
+
+	finished := make(chan error, 1)
+	handler := timeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		// Give the middleware time to send the timeout response
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain")
+		_, err := w.Write([]byte("too late"))
+		finished <- err
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/categories", nil))
+	if err := <-finished; err != http.ErrHandlerTimeout {
+		t.Errorf("expected the late write to fail with ErrHandlerTimeout, got %v", err)
+	}
+
+	if rr.Code != http.StatusRequestTimeout || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a 408 JSON response, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || errResp.Type != ErrorTypeTimeout {
+		t.Errorf("expected only the timeout error in the body, got %q", rr.Body.String())
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"GZIP":                true,
+		"deflate, gzip":       true,
+		"gzip;q=0.5":          true,
+		"gzip; q=0":           false,
+		"gzip;q=0.0, deflate": false,
+		"br, deflate":         false,
+		"x-gzip-custom":       false,
+	}
+
+	for header, expected := range tests {
+		if got := acceptsGzip(header); got != expected {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, expected)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestIPConcurrencyLimiterRejectsExcessRequests(t *testing.T) {
+	limiter := newIPConcurrencyLimiter(2)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(remoteAddr string) int {
+		req := httptest.NewRequest("POST", "/v1/analyze", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Fill both slots for one IP and one slot for another IP
+	var wg sync.WaitGroup
+	blockedCodes := make(chan int, 3)
+	for _, addr := range []string{"203.0.113.7:1001", "203.0.113.7:1002", "198.51.100.1:1001"} {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			blockedCodes <- serve(addr)
+		}(addr)
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+
+	// Further concurrent requests from the saturated IP are rejected
+	var rejected sync.WaitGroup
+	rejectedCodes := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		rejected.Add(1)
+		go func() {
+			defer rejected.Done()
+			rejectedCodes <- serve("203.0.113.7:2000")
+		}()
+	}
+	rejected.Wait()
+	close(rejectedCodes)
+
+	for code := range rejectedCodes {
+		if code != http.StatusTooManyRequests {
+			t.Errorf("expected status 429 for excess request, got %d", code)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	close(blockedCodes)
+
+	for code := range blockedCodes {
+		if code != http.StatusOK {
+			t.Errorf("expected admitted request to succeed, got %d", code)
+		}
+	}
+
+	if n := limiter.count("203.0.113.7"); n != 0 {
+		t.Errorf("in-flight counter should return to 0, got %d", n)
+	}
+}
+
+func TestIPConcurrencyLimiterReleasesOnPanic(t *testing.T) {
+	limiter := newIPConcurrencyLimiter(1)
+
+	handler := errorHandlingMiddleware(limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failure")
+	})))
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/v1/analyze", nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		// Each request should reach the panicking handler rather than be rate limited
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("request %d: expected status 500, got %d", i+1, rr.Code)
+		}
+	}
+
+	if n := limiter.count("203.0.113.9"); n != 0 {
+		t.Errorf("in-flight counter should be released after panic, got %d", n)
+	}
+}
+
+func TestIPConcurrencyLimiterDisabled(t *testing.T) {
+	limiter := newIPConcurrencyLimiter(0)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "request_id", "test-request-id"))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("disabled limiter should pass requests through, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the runtime settings that can be tuned per deployment
+type Config struct {
+	Port           string
+	MaxBodyBytes   int64
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	AnalyzeTimeout time.Duration
+	// FetchMaxAttempts is the total number of tries for a transiently failing fetch
+	FetchMaxAttempts int
+	// FetchProxy routes outbound fetches through an http(s):// or socks5:// proxy,
+	// overriding HTTP_PROXY/HTTPS_PROXY/NO_PROXY when set
+	FetchProxy string
+	// ScanProfilesFile optionally points to a JSON file of additional scan profiles
+	ScanProfilesFile string
+	// TrackersFile optionally points to a JSON file of additional tracker signatures
+	TrackersFile string
+	// ConsentSignaturesFile optionally points to a JSON file of additional consent-management platforms
+	ConsentSignaturesFile string
+	// Soft404PatternsFile optionally points to a JSON array of additional soft 404 indicators
+	Soft404PatternsFile string
+	// MaxConcurrentPerIP caps in-flight requests from a single client IP; zero disables the cap
+	MaxConcurrentPerIP int
+	// PageStoreDir enables keeping raw fetched pages for re-analysis when set
+	PageStoreDir string
+	// PageStoreMaxBytes is the largest page body kept in the page store
+	PageStoreMaxBytes int64
+	// ReadyCheckHost, when set, is resolved by /ready to confirm outbound DNS works
+	ReadyCheckHost string
+	// LogSampleRate logs 1 in N successful requests; failed requests are always logged
+	LogSampleRate int
+	// JobWorkers is the number of async analysis jobs run at once
+	JobWorkers int
+	// JobStoreMax caps the async jobs held in memory, queued or finished
+	JobStoreMax int
+	// JobTTL is how long finished async jobs stay available for polling
+	JobTTL time.Duration
+	// JobTimeout is the default and maximum analysis budget for an async job
+	JobTimeout time.Duration
+	// CallbackSecret signs callback deliveries; callback_url is rejected while it is unset
+	CallbackSecret string
+	// SensitivePaths are the paths probed when probe_sensitive_paths is requested
+	SensitivePaths []string
+	// MaxAnalyzers caps the analyzers a single request may enable, with sensitive path
+	// probing counting as one; zero allows them all
+	MaxAnalyzers int
+	// KeywordStuffingThreshold is the term density, in percent, above which a page is
+	// flagged for keyword stuffing
+	KeywordStuffingThreshold float64
+	// ReadingWordsPerMinute is the reading speed behind the keywords reading time estimate
+	ReadingWordsPerMinute int
+	// Probe bounds the probes an analysis sends besides the page fetch
+	Probe ProbeConfig
+	// ProbeCacheSize caps the probe results kept per site; zero disables the cache
+	ProbeCacheSize int
+	// ProbeCacheTTL is how long a site's probe results are reused
+	ProbeCacheTTL time.Duration
+}
+
+// appConfig is the active configuration, replaced by main() at startup
+var appConfig = defaultConfig()
+
+// defaultConfig returns the built-in settings used when no overrides are provided
+func defaultConfig() Config {
+	return Config{
+		Port:                     "8080",
+		MaxBodyBytes:             5 * 1024 * 1024, // 5MB limit for memory optimization
+		ReadTimeout:              10 * time.Second,
+		WriteTimeout:             30 * time.Second,
+		AnalyzeTimeout:           20 * time.Second,
+		FetchMaxAttempts:         2,
+		MaxConcurrentPerIP:       10,
+		LogSampleRate:            1,
+		PageStoreMaxBytes:        1024 * 1024,
+		JobWorkers:               4,
+		JobStoreMax:              1000,
+		JobTTL:                   time.Hour,
+		JobTimeout:               2 * time.Minute,
+		SensitivePaths:           defaultSensitivePaths(),
+		KeywordStuffingThreshold: 4,
+		ReadingWordsPerMinute:    200,
+		Probe:                    defaultProbeConfig(),
+		ProbeCacheSize:           1000,
+		ProbeCacheTTL:            10 * time.Minute,
+	}
+}
+
+// loadConfig builds the configuration from the process environment
+func loadConfig() (Config, error) {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	return parseConfig(env)
+}
+
+// parseConfig builds the configuration from a key/value map, falling back to defaults for unset keys
+func parseConfig(env map[string]string) (Config, error) {
+	cfg := defaultConfig()
+
+	if port := strings.TrimSpace(env["PORT"]); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return cfg, fmt.Errorf("PORT must be a number between 1 and 65535, got %q", port)
+		}
+		cfg.Port = port
+	}
+
+	if value := strings.TrimSpace(env["MAX_BODY_BYTES"]); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("MAX_BODY_BYTES must be a positive integer, got %q", value)
+		}
+		cfg.MaxBodyBytes = n
+	}
+
+	if value := strings.TrimSpace(env["MAX_CONCURRENT_PER_IP"]); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("MAX_CONCURRENT_PER_IP must be a non-negative integer, got %q", value)
+		}
+		cfg.MaxConcurrentPerIP = n
+	}
+
+	if value := strings.TrimSpace(env["MAX_ANALYZERS"]); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("MAX_ANALYZERS must be a non-negative integer, got %q", value)
+		}
+		cfg.MaxAnalyzers = n
+	}
+
+	if value := strings.TrimSpace(env["PROBE_CACHE_SIZE"]); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("PROBE_CACHE_SIZE must be a non-negative integer, got %q", value)
+		}
+		cfg.ProbeCacheSize = n
+	}
+
+	if value := strings.TrimSpace(env["KEYWORD_STUFFING_THRESHOLD"]); value != "" {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n <= 0 || n > 100 {
+			return cfg, fmt.Errorf("KEYWORD_STUFFING_THRESHOLD must be a percentage above 0 and at most 100, got %q", value)
+		}
+		cfg.KeywordStuffingThreshold = n
+	}
+
+	if value := strings.TrimSpace(env["PAGE_STORE_MAX_BYTES"]); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("PAGE_STORE_MAX_BYTES must be a positive integer, got %q", value)
+		}
+		cfg.PageStoreMaxBytes = n
+	}
+
+	if value := strings.TrimSpace(env["LOG_SAMPLE_RATE"]); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("LOG_SAMPLE_RATE must be a positive integer, got %q", value)
+		}
+		cfg.LogSampleRate = n
+	}
+
+	positiveInts := []struct {
+		key    string
+		target *int
+	}{
+		{"JOB_WORKERS", &cfg.JobWorkers},
+		{"JOB_STORE_MAX", &cfg.JobStoreMax},
+		{"PROBE_MAX_ATTEMPTS", &cfg.Probe.MaxAttempts},
+		{"READING_WORDS_PER_MINUTE", &cfg.ReadingWordsPerMinute},
+	}
+	for _, p := range positiveInts {
+		value := strings.TrimSpace(env[p.key])
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("%s must be a positive integer, got %q", p.key, value)
+		}
+		*p.target = n
+	}
+
+	if value := strings.TrimSpace(env["FETCH_MAX_ATTEMPTS"]); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("FETCH_MAX_ATTEMPTS must be a positive integer, got %q", value)
+		}
+		cfg.FetchMaxAttempts = n
+	}
+
+	if value := strings.TrimSpace(env["FETCH_PROXY"]); value != "" {
+		if _, err := parseProxyURL(value); err != nil {
+			return cfg, fmt.Errorf("FETCH_PROXY is invalid: %v", err)
+		}
+		cfg.FetchProxy = value
+	}
+
+	cfg.ScanProfilesFile = strings.TrimSpace(env["SCAN_PROFILES_FILE"])
+	cfg.TrackersFile = strings.TrimSpace(env["TRACKERS_FILE"])
+	cfg.ConsentSignaturesFile = strings.TrimSpace(env["CONSENT_SIGNATURES_FILE"])
+	cfg.Soft404PatternsFile = strings.TrimSpace(env["SOFT_404_PATTERNS_FILE"])
+	cfg.PageStoreDir = strings.TrimSpace(env["PAGE_STORE_DIR"])
+	cfg.ReadyCheckHost = strings.TrimSpace(env["READY_CHECK_HOST"])
+	cfg.CallbackSecret = env["CALLBACK_SECRET"]
+
+	if value := strings.TrimSpace(env["SENSITIVE_PATHS"]); value != "" {
+		var paths []string
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if !strings.HasPrefix(path, "/") {
+				return cfg, fmt.Errorf("SENSITIVE_PATHS entries must start with /, got %q", path)
+			}
+			paths = append(paths, path)
+		}
+		cfg.SensitivePaths = paths
+	}
+
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{"READ_TIMEOUT", &cfg.ReadTimeout},
+		{"WRITE_TIMEOUT", &cfg.WriteTimeout},
+		{"ANALYZE_TIMEOUT", &cfg.AnalyzeTimeout},
+		{"JOB_TTL", &cfg.JobTTL},
+		{"JOB_TIMEOUT", &cfg.JobTimeout},
+		{"PROBE_TIMEOUT", &cfg.Probe.Timeout},
+		{"PROBE_RETRY_BACKOFF", &cfg.Probe.Backoff},
+		{"PROBE_CACHE_TTL", &cfg.ProbeCacheTTL},
+	}
+	for _, d := range durations {
+		value := strings.TrimSpace(env[d.key])
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return cfg, fmt.Errorf("%s must be a positive duration such as 30s, got %q", d.key, value)
+		}
+		*d.target = parsed
+	}
+
+	return cfg, nil
+}
+
+// parseProxyURL validates a proxy URL, accepting http, https, socks5 and socks5h schemes
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL must include a host")
+	}
+
+	return proxyURL, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Cookie issues reported by analyzeCookies
+const (
+	cookieIssueMissingSecure   = "missing_secure"
+	cookieIssueMissingHTTPOnly = "missing_httponly"
+	cookieIssueMissingSameSite = "missing_samesite"
+	cookieIssueSameSiteNone    = "samesite_none_without_secure"
+)
+
+var cookieRecommendations = map[string]string{
+	cookieIssueMissingSecure:   "Set the Secure attribute so the cookie is only sent over HTTPS",
+	cookieIssueMissingHTTPOnly: "Set the HttpOnly attribute so scripts cannot read the cookie",
+	cookieIssueMissingSameSite: "Set SameSite=Lax or SameSite=Strict to limit cross-site requests",
+	cookieIssueSameSiteNone:    "SameSite=None requires the Secure attribute; browsers reject the cookie otherwise",
+}
+
+// CookieAnalysis describes the security attributes of one Set-Cookie header. Values are never reported.
+type CookieAnalysis struct {
+	Name            string   `json:"name"`
+	Secure          bool     `json:"secure"`
+	HTTPOnly        bool     `json:"http_only"`
+	SameSite        string   `json:"same_site,omitempty"`
+	SessionLike     bool     `json:"session_like"`
+	Issues          []string `json:"issues,omitempty"`
+	Recommendations []string `json:"recommendations,omitempty"`
+}
+
+// analyzeCookies checks every Set-Cookie header for missing security attributes
+func analyzeCookies(header http.Header) []CookieAnalysis {
+	cookies := (&http.Response{Header: header}).Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+
+	analyses := make([]CookieAnalysis, 0, len(cookies))
+	for _, cookie := range cookies {
+		analysis := CookieAnalysis{
+			Name:        cookie.Name,
+			Secure:      cookie.Secure,
+			HTTPOnly:    cookie.HttpOnly,
+			SameSite:    sameSiteName(cookie.SameSite),
+			SessionLike: isSessionCookieName(cookie.Name),
+		}
+
+		if !cookie.Secure {
+			if cookie.SameSite == http.SameSiteNoneMode {
+				analysis.Issues = append(analysis.Issues, cookieIssueSameSiteNone)
+			} else {
+				analysis.Issues = append(analysis.Issues, cookieIssueMissingSecure)
+			}
+		}
+		if !cookie.HttpOnly {
+			analysis.Issues = append(analysis.Issues, cookieIssueMissingHTTPOnly)
+		}
+		if analysis.SameSite == "" {
+			analysis.Issues = append(analysis.Issues, cookieIssueMissingSameSite)
+		}
+		for _, issue := range analysis.Issues {
+			analysis.Recommendations = append(analysis.Recommendations, cookieRecommendations[issue])
+		}
+
+		analyses = append(analyses, analysis)
+	}
+	return analyses
+}
+
+// cookieFindings turns cookie issues into security findings: session cookies
+// missing Secure or HttpOnly are high severity, and SameSite=None without Secure is medium
+func cookieFindings(cookies []CookieAnalysis) []SecurityFinding {
+	var findings []SecurityFinding
+	for _, cookie := range cookies {
+		var missing []string
+		sameSiteNone := false
+		for _, issue := range cookie.Issues {
+			switch issue {
+			case cookieIssueMissingSecure:
+				missing = append(missing, "Secure")
+			case cookieIssueMissingHTTPOnly:
+				missing = append(missing, "HttpOnly")
+			case cookieIssueSameSiteNone:
+				missing = append(missing, "Secure")
+				sameSiteNone = true
+			}
+		}
+
+		switch {
+		case cookie.SessionLike && len(missing) > 0:
+			findings = append(findings, SecurityFinding{
+				Type:     "insecure_session_cookie",
+				Severity: "high",
+				Subject:  cookie.Name,
+				Message:  fmt.Sprintf("Session cookie %q is missing %s", cookie.Name, strings.Join(missing, " and ")),
+			})
+		case sameSiteNone:
+			findings = append(findings, SecurityFinding{
+				Type:     "samesite_none_without_secure",
+				Severity: "medium",
+				Subject:  cookie.Name,
+				Message:  fmt.Sprintf("Cookie %q sets SameSite=None without Secure", cookie.Name),
+			})
+		}
+	}
+	return findings
+}
+
+// isSessionCookieName reports whether a cookie name suggests it holds a session or token
+func isSessionCookieName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "session") ||
+		strings.Contains(lower, "sessid") ||
+		strings.Contains(lower, "token") ||
+		strings.HasSuffix(lower, "sid")
+}
+
+// sameSiteName returns the SameSite attribute value, or "" when it is not set
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	default:
+		return ""
+	}
+}
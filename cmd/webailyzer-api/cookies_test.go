@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setCookieHeader(values ...string) http.Header {
+	header := make(http.Header)
+	for _, value := range values {
+		header.Add("Set-Cookie", value)
+	}
+	return header
+}
+
+func TestAnalyzeCookies(t *testing.T) {
+	tests := []struct {
+		name      string
+		setCookie string
+		issues    []string
+		session   bool
+	}{
+		{
+			name:      "secure cookie",
+			setCookie: "prefs=dark; Path=/; Secure; HttpOnly; SameSite=Lax",
+			issues:    nil,
+		},
+		{
+			name:      "insecure cookie",
+			setCookie: "prefs=dark; Path=/",
+			issues:    []string{cookieIssueMissingSecure, cookieIssueMissingHTTPOnly, cookieIssueMissingSameSite},
+		},
+		{
+			name:      "SameSite=None without Secure",
+			setCookie: "tracking=1; HttpOnly; SameSite=None",
+			issues:    []string{cookieIssueSameSiteNone},
+		},
+		{
+			name:      "session cookie missing HttpOnly",
+			setCookie: "PHPSESSID=abc123; Secure; SameSite=Strict",
+			issues:    []string{cookieIssueMissingHTTPOnly},
+			session:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookies := analyzeCookies(setCookieHeader(tt.setCookie))
+			if len(cookies) != 1 {
+				t.Fatalf("expected 1 cookie, got %d", len(cookies))
+			}
+
+			cookie := cookies[0]
+			if strings.Join(cookie.Issues, ",") != strings.Join(tt.issues, ",") {
+				t.Errorf("expected issues %v, got %v", tt.issues, cookie.Issues)
+			}
+			if len(cookie.Recommendations) != len(cookie.Issues) {
+				t.Errorf("expected a recommendation per issue, got %v", cookie.Recommendations)
+			}
+			if cookie.SessionLike != tt.session {
+				t.Errorf("expected session-like %v for %s", tt.session, cookie.Name)
+			}
+		})
+	}
+}
+
+func TestCookieFindings(t *testing.T) {
+	cookies := analyzeCookies(setCookieHeader(
+		"sessionid=abc; Path=/",
+		"auth_token=xyz; Secure; HttpOnly; SameSite=Lax",
+		"ads=1; HttpOnly; SameSite=None",
+		"theme=light",
+	))
+
+	findings := cookieFindings(cookies)
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %d: %+v", len(findings), findings)
+	}
+
+	if findings[0].Type != "insecure_session_cookie" || findings[0].Severity != "high" {
+		t.Errorf("expected a high severity session cookie finding, got %+v", findings[0])
+	}
+	if !strings.Contains(findings[0].Message, `"sessionid" is missing Secure and HttpOnly`) {
+		t.Errorf("unexpected message: %s", findings[0].Message)
+	}
+	if findings[1].Type != "samesite_none_without_secure" || findings[1].Severity != "medium" {
+		t.Errorf("expected a medium SameSite=None finding, got %+v", findings[1])
+	}
+}
+
+func TestAnalyzeHandlerReportsCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-value"})
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Cookies</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	rr := postAnalyze(t, map[string]string{"url": server.URL})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "secret-value") {
+		t.Error("cookie values must not appear in the response")
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Cookies) != 1 || response.Cookies[0].Name != "session" {
+		t.Fatalf("expected the session cookie to be analyzed, got %+v", response.Cookies)
+	}
+	if len(response.SecurityFindings) != 1 || response.SecurityFindings[0].Type != "insecure_session_cookie" {
+		t.Errorf("expected an insecure_session_cookie finding, got %+v", response.SecurityFindings)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// corsProbeOrigin is the Origin sent by probeCORSOrigin. The .invalid TLD never
+// resolves, so no site has a legitimate reason to trust it.
+const corsProbeOrigin = "https://webailyzer-probe.invalid"
+
+// detectCORSMisconfigurations flags Access-Control-Allow-Origin values that let
+// untrusted origins read responses. Only the fetched response is inspected; origin
+// reflection needs a request carrying an Origin header, see probeCORSOrigin.
+func detectCORSMisconfigurations(header http.Header) []SecurityFinding {
+	origin := strings.TrimSpace(header.Get("Access-Control-Allow-Origin"))
+	if origin == "" {
+		return nil
+	}
+	credentials := strings.EqualFold(strings.TrimSpace(header.Get("Access-Control-Allow-Credentials")), "true")
+
+	switch {
+	case origin == "*" && credentials:
+		return []SecurityFinding{{
+			Type:     "cors_wildcard_with_credentials",
+			Severity: "high",
+			Subject:  "Access-Control-Allow-Origin",
+			Message:  "Access-Control-Allow-Origin: * is combined with Access-Control-Allow-Credentials: true; restrict allowed origins to an explicit list of trusted origins",
+		}}
+	case strings.EqualFold(origin, "null"):
+		severity := "medium"
+		if credentials {
+			severity = "high"
+		}
+		return []SecurityFinding{{
+			Type:     "cors_null_origin",
+			Severity: severity,
+			Subject:  "Access-Control-Allow-Origin",
+			Message:  "Access-Control-Allow-Origin: null can be matched by sandboxed iframes and local files; allow only explicit trusted origins",
+		}}
+	case strings.Contains(origin, ",") || strings.Contains(origin, " "):
+		return []SecurityFinding{{
+			Type:     "cors_invalid_origin",
+			Severity: "low",
+			Subject:  "Access-Control-Allow-Origin",
+			Message:  "Access-Control-Allow-Origin must be a single origin; return the matching trusted origin per request instead of a list",
+		}}
+	}
+	return nil
+}
+
+// probeCORSOrigin requests pageURL again with corsProbeOrigin as the Origin and
+// reports a finding when the response allows it, which means the site reflects any
+// origin it is sent. Redirects are not followed and failed requests report nothing.
+// The probe is bounded by cfg.Timeout and returns the context error when cut off.
+func probeCORSOrigin(ctx context.Context, pageURL string, cfg ProbeConfig) ([]SecurityFinding, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, nil
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	req.Header.Set("Origin", corsProbeOrigin)
+	resp, err := doProbe(ctx, probeClient(), req, cfg)
+	if err != nil {
+		return nil, ctx.Err()
+	}
+	return detectOriginReflection(resp.Header, corsProbeOrigin), nil
+}
+
+// detectOriginReflection flags a response that allows origin, an origin the site
+// cannot have chosen to trust. Allowing credentials as well lets any site read
+// authenticated responses, so that is reported as high severity.
+func detectOriginReflection(header http.Header, origin string) []SecurityFinding {
+	if strings.TrimSpace(header.Get("Access-Control-Allow-Origin")) != origin {
+		return nil
+	}
+
+	severity := "medium"
+	message := "Access-Control-Allow-Origin echoes the request Origin, so any site can read responses; check origins against an explicit list of trusted origins"
+	if strings.EqualFold(strings.TrimSpace(header.Get("Access-Control-Allow-Credentials")), "true") {
+		severity = "high"
+		message = "Access-Control-Allow-Origin echoes the request Origin with Access-Control-Allow-Credentials: true, so any site can read authenticated responses; check origins against an explicit list of trusted origins"
+	}
+	return []SecurityFinding{{
+		Type:     "cors_origin_reflected",
+		Severity: severity,
+		Subject:  "Access-Control-Allow-Origin",
+		Message:  message,
+	}}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDetectCORSMisconfigurations(t *testing.T) {
+	tests := []struct {
+		name        string
+		origin      string
+		credentials string
+		findingType string
+		severity    string
+	}{
+		{name: "no CORS headers"},
+		{name: "wildcard without credentials", origin: "*"},
+		{name: "explicit origin with credentials", origin: "https://app.example.com", credentials: "true"},
+		{name: "wildcard with credentials", origin: "*", credentials: "true", findingType: "cors_wildcard_with_credentials", severity: "high"},
+		{name: "wildcard with credentials, mixed case", origin: "*", credentials: "TRUE", findingType: "cors_wildcard_with_credentials", severity: "high"},
+		{name: "null origin", origin: "null", findingType: "cors_null_origin", severity: "medium"},
+		{name: "null origin with credentials", origin: "null", credentials: "true", findingType: "cors_null_origin", severity: "high"},
+		{name: "origin list", origin: "https://a.example, https://b.example", findingType: "cors_invalid_origin", severity: "low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			if tt.origin != "" {
+				header.Set("Access-Control-Allow-Origin", tt.origin)
+			}
+			if tt.credentials != "" {
+				header.Set("Access-Control-Allow-Credentials", tt.credentials)
+			}
+
+			findings := detectCORSMisconfigurations(header)
+			if tt.findingType == "" {
+				if len(findings) != 0 {
+					t.Errorf("expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("expected 1 finding, got %+v", findings)
+			}
+			if findings[0].Type != tt.findingType || findings[0].Severity != tt.severity {
+				t.Errorf("expected %s/%s, got %s/%s", tt.findingType, tt.severity, findings[0].Type, findings[0].Severity)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerReportsCORSMisconfiguration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>CORS</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	rr := postAnalyze(t, map[string]string{"url": server.URL})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(response.SecurityFindings) != 1 || response.SecurityFindings[0].Type != "cors_wildcard_with_credentials" {
+		t.Errorf("expected a cors_wildcard_with_credentials finding, got %+v", response.SecurityFindings)
+	}
+}
+
+func TestDetectOriginReflection(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     string
+		credentials string
+		severity    string
+	}{
+		{name: "no CORS headers"},
+		{name: "wildcard", allowed: "*"},
+		{name: "fixed trusted origin", allowed: "https://app.example.com", credentials: "true"},
+		{name: "probe origin echoed", allowed: corsProbeOrigin, severity: "medium"},
+		{name: "probe origin echoed with credentials", allowed: corsProbeOrigin, credentials: "true", severity: "high"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			if tt.allowed != "" {
+				header.Set("Access-Control-Allow-Origin", tt.allowed)
+			}
+			if tt.credentials != "" {
+				header.Set("Access-Control-Allow-Credentials", tt.credentials)
+			}
+
+			findings := detectOriginReflection(header, corsProbeOrigin)
+			if tt.severity == "" {
+				if len(findings) != 0 {
+					t.Errorf("expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 || findings[0].Type != "cors_origin_reflected" || findings[0].Severity != tt.severity {
+				t.Errorf("expected a %s cors_origin_reflected finding, got %+v", tt.severity, findings)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerProbesCORSOriginReflection(t *testing.T) {
+	var origins []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			origins = append(origins, origin)
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>CORS</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	for _, probe := range []bool{false, true} {
+		origins = nil
+		rr := postAnalyze(t, map[string]interface{}{"url": server.URL, "probe_cors": probe})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		var response AnalyzeResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if !probe {
+			if len(origins) != 0 || len(response.SecurityFindings) != 0 {
+				t.Errorf("expected no probe without probe_cors, got origins %v and findings %+v", origins, response.SecurityFindings)
+			}
+			continue
+		}
+		if len(origins) != 1 || origins[0] != corsProbeOrigin {
+			t.Errorf("expected one probe with Origin %s, got %v", corsProbeOrigin, origins)
+		}
+		if len(response.SecurityFindings) != 1 || response.SecurityFindings[0].Type != "cors_origin_reflected" || response.SecurityFindings[0].Severity != "high" {
+			t.Errorf("expected a high cors_origin_reflected finding, got %+v", response.SecurityFindings)
+		}
+		if _, ok := response.Timings["cors_probe"]; !ok {
+			t.Errorf("expected a cors_probe timing, got %v", response.Timings)
+		}
+	}
+}
+
+func TestProbeCORSOriginRetriesFlakyTarget(t *testing.T) {
+	var probes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if probes.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	}))
+	defer server.Close()
+
+	cfg := defaultProbeConfig()
+	cfg.Backoff = time.Millisecond
+
+	findings, err := probeCORSOrigin(context.Background(), server.URL, cfg)
+	if err != nil || !hasFinding(findings, "cors_origin_reflected") {
+		t.Errorf("expected the retry to find the reflected origin, got %+v (err %v)", findings, err)
+	}
+	if n := probes.Load(); n != 2 {
+		t.Errorf("expected 2 probe requests, got %d", n)
+	}
+}
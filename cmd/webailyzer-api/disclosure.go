@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// productVersionPattern matches product/version tokens such as "nginx/1.25.3" or "PHP/8.2.1"
+var productVersionPattern = regexp.MustCompile(`([A-Za-z][A-Za-z0-9._+-]*)/v?(\d+(?:\.[0-9A-Za-z]+)*)`)
+
+// bareVersionPattern matches headers whose whole value is a version, such as X-AspNet-Version
+var bareVersionPattern = regexp.MustCompile(`^v?(\d+(?:\.\d+)+)$`)
+
+// versionDisclosureHeader is a response header that can leak software versions
+type versionDisclosureHeader struct {
+	Name string
+	// Product names the software for headers whose value is only a version number
+	Product  string
+	Severity string
+}
+
+// versionDisclosureHeaders lists the headers checked for version tokens. Exact framework
+// versions are rated higher since they map directly to known vulnerabilities.
+var versionDisclosureHeaders = []versionDisclosureHeader{
+	{Name: "Server", Severity: "low"},
+	{Name: "X-Powered-By", Severity: "low"},
+	{Name: "X-AspNet-Version", Product: "ASP.NET", Severity: "medium"},
+	{Name: "X-AspNetMvc-Version", Product: "ASP.NET MVC", Severity: "medium"},
+}
+
+// detectVersionDisclosure flags response headers that expose product versions, which
+// help attackers match a site against known vulnerabilities. Product names without a
+// version are not flagged.
+func detectVersionDisclosure(header http.Header) []SecurityFinding {
+	var findings []SecurityFinding
+	for _, h := range versionDisclosureHeaders {
+		value := strings.TrimSpace(header.Get(h.Name))
+		if value == "" {
+			continue
+		}
+
+		var versions []string
+		if h.Product != "" {
+			if m := bareVersionPattern.FindStringSubmatch(value); m != nil {
+				versions = append(versions, h.Product+" "+m[1])
+			}
+		}
+		for _, m := range productVersionPattern.FindAllStringSubmatch(value, -1) {
+			versions = append(versions, m[1]+" "+m[2])
+		}
+		if len(versions) == 0 {
+			continue
+		}
+
+		findings = append(findings, SecurityFinding{
+			Type:     "information_disclosure",
+			Severity: h.Severity,
+			Subject:  h.Name,
+			Message: fmt.Sprintf("The %s header discloses %s; configure the server to omit version numbers from this header",
+				h.Name, strings.Join(versions, ", ")),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDetectVersionDisclosure(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		severity string
+		// versions are the product/version pairs expected in the message; empty means no finding
+		versions []string
+	}{
+		{name: "server without version", header: "Server", value: "nginx"},
+		{name: "server with version", header: "Server", value: "nginx/1.25.3", severity: "low", versions: []string{"nginx 1.25.3"}},
+		{name: "server with several products", header: "Server", value: "Apache/2.4.57 (Ubuntu) OpenSSL/3.0.2", severity: "low", versions: []string{"Apache 2.4.57", "OpenSSL 3.0.2"}},
+		{name: "cloudflare", header: "Server", value: "cloudflare"},
+		{name: "powered by without version", header: "X-Powered-By", value: "Express"},
+		{name: "powered by with version", header: "X-Powered-By", value: "PHP/8.2.1", severity: "low", versions: []string{"PHP 8.2.1"}},
+		{name: "aspnet version", header: "X-AspNet-Version", value: "4.0.30319", severity: "medium", versions: []string{"ASP.NET 4.0.30319"}},
+		{name: "aspnet mvc version", header: "X-AspNetMvc-Version", value: "5.2", severity: "medium", versions: []string{"ASP.NET MVC 5.2"}},
+		{name: "aspnet header without version", header: "X-AspNet-Version", value: "hidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			header.Set(tt.header, tt.value)
+
+			findings := detectVersionDisclosure(header)
+			if len(tt.versions) == 0 {
+				if len(findings) != 0 {
+					t.Errorf("expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("expected 1 finding, got %+v", findings)
+			}
+			f := findings[0]
+			if f.Type != "information_disclosure" || f.Severity != tt.severity {
+				t.Errorf("expected information_disclosure/%s, got %s/%s", tt.severity, f.Type, f.Severity)
+			}
+			for _, version := range tt.versions {
+				if !strings.Contains(f.Message, version) {
+					t.Errorf("expected message to mention %q, got %q", version, f.Message)
+				}
+			}
+			if !strings.Contains(f.Message, tt.header) {
+				t.Errorf("expected message to name the %s header, got %q", tt.header, f.Message)
+			}
+		})
+	}
+}
+
+func TestDetectVersionDisclosureReportsEachHeader(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Server", "Microsoft-IIS/10.0")
+	header.Set("X-Powered-By", "ASP.NET")
+	header.Set("X-AspNet-Version", "4.0.30319")
+
+	findings := detectVersionDisclosure(header)
+	if len(findings) != 2 {
+		t.Fatalf("expected findings for Server and X-AspNet-Version only, got %+v", findings)
+	}
+	if !strings.Contains(findings[0].Message, "Microsoft-IIS 10.0") || !strings.Contains(findings[1].Message, "ASP.NET 4.0.30319") {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// LoadTimeMetrics breaks the fetch of the analyzed page into its network phases, in milliseconds.
+// Phases of every hop in a redirect chain are summed; reused connections skip DNS, connect and TLS.
+type LoadTimeMetrics struct {
+	DNSLookupTime    float64 `json:"dns_lookup_ms"`
+	ConnectionTime   float64 `json:"connection_ms"`
+	TLSHandshakeTime float64 `json:"tls_handshake_ms"`
+	ServerTime       float64 `json:"server_ms"`
+	TransferTime     float64 `json:"transfer_ms"`
+}
+
+// fetchPhases holds the measured phase durations of a fetch
+type fetchPhases struct {
+	DNSLookup    time.Duration
+	Connection   time.Duration
+	TLSHandshake time.Duration
+	Server       time.Duration // time to first byte after the request was written
+	Transfer     time.Duration // reading the body of the final response
+}
+
+// Metrics converts the phases to the millisecond form reported in responses
+func (p fetchPhases) Metrics() *LoadTimeMetrics {
+	return &LoadTimeMetrics{
+		DNSLookupTime:    durationMs(p.DNSLookup),
+		ConnectionTime:   durationMs(p.Connection),
+		TLSHandshakeTime: durationMs(p.TLSHandshake),
+		ServerTime:       durationMs(p.Server),
+		TransferTime:     durationMs(p.Transfer),
+	}
+}
+
+// fetchTracer records phase timings through an httptrace.ClientTrace. Callbacks can
+// arrive from the transport's dial goroutines, so all state is guarded by mu.
+type fetchTracer struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	phases       fetchPhases
+}
+
+// fetchTracerKey is the context key under which a *fetchTracer is stored
+type fetchTracerKey struct{}
+
+// withFetchTracer returns a context that records phase timings into tr
+func withFetchTracer(ctx context.Context, tr *fetchTracer) context.Context {
+	ctx = context.WithValue(ctx, fetchTracerKey{}, tr)
+	return httptrace.WithClientTrace(ctx, tr.clientTrace())
+}
+
+func (tr *fetchTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tr.mu.Lock()
+			tr.dnsStart = time.Now()
+			tr.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tr.mu.Lock()
+			tr.phases.DNSLookup += since(&tr.dnsStart)
+			tr.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			tr.mu.Lock()
+			// Parallel dials (Happy Eyeballs) count once, from the first start to the first success
+			if tr.connectStart.IsZero() {
+				tr.connectStart = time.Now()
+			}
+			tr.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				return
+			}
+			tr.mu.Lock()
+			tr.phases.Connection += since(&tr.connectStart)
+			tr.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			tr.mu.Lock()
+			tr.tlsStart = time.Now()
+			tr.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tr.mu.Lock()
+			tr.phases.TLSHandshake += since(&tr.tlsStart)
+			tr.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			tr.mu.Lock()
+			tr.wroteRequest = time.Now()
+			tr.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tr.mu.Lock()
+			tr.phases.Server += since(&tr.wroteRequest)
+			tr.mu.Unlock()
+		},
+	}
+}
+
+// since returns the time elapsed from *start and clears it, or zero when no phase is in progress
+func since(start *time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+	d := time.Since(*start)
+	*start = time.Time{}
+	return d
+}
+
+// reset discards the timings of a previous attempt
+func (tr *fetchTracer) reset() {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.dnsStart, tr.connectStart, tr.tlsStart, tr.wroteRequest = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	tr.phases = fetchPhases{}
+}
+
+// setTransfer records how long reading the response body took
+func (tr *fetchTracer) setTransfer(d time.Duration) {
+	tr.mu.Lock()
+	tr.phases.Transfer = d
+	tr.mu.Unlock()
+}
+
+// Phases returns the timings recorded so far
+func (tr *fetchTracer) Phases() fetchPhases {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.phases
+}
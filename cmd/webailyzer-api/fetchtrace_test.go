@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowPageHandler delays the first byte and then streams the body in two parts,
+// so both server time and transfer time are measurable
+func slowPageHandler(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(20 * time.Millisecond)
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte("<html><head><title>Traced</title></head>"))
+	w.(http.Flusher).Flush()
+	time.Sleep(20 * time.Millisecond)
+	w.Write([]byte("<body>done</body></html>"))
+}
+
+func TestFetchPageRecordsPhases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(slowPageHandler))
+	defer server.Close()
+
+	// Use a host name so the fetch includes a DNS lookup
+	pageURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	opts := ScanOptions{UserAgent: defaultUserAgent, Timeout: 5 * time.Second}
+
+	page, apiErr := fetchPage(context.Background(), "test-request-id", AnalyzeRequest{URL: pageURL}, opts)
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %s: %s", apiErr.Message, apiErr.Details)
+	}
+
+	phases := page.Phases
+	if phases.DNSLookup <= 0 {
+		t.Errorf("expected a DNS lookup time, got %v", phases.DNSLookup)
+	}
+	if phases.Connection <= 0 {
+		t.Errorf("expected a connection time, got %v", phases.Connection)
+	}
+	if phases.Server < 20*time.Millisecond {
+		t.Errorf("expected server time of at least 20ms, got %v", phases.Server)
+	}
+	// The body read starts a little after the first byte, so allow for part of the pause being missed
+	if phases.Transfer < 10*time.Millisecond {
+		t.Errorf("expected transfer time to cover the pause mid-body, got %v", phases.Transfer)
+	}
+	if phases.TLSHandshake != 0 {
+		t.Errorf("expected no TLS handshake for plain HTTP, got %v", phases.TLSHandshake)
+	}
+}
+
+func TestFetchPageRecordsTLSHandshake(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(slowPageHandler))
+	defer server.Close()
+
+	original := httpClient
+	httpClient = server.Client()
+	defer func() { httpClient = original }()
+
+	opts := ScanOptions{UserAgent: defaultUserAgent, Timeout: 5 * time.Second}
+	page, apiErr := fetchPage(context.Background(), "test-request-id", AnalyzeRequest{URL: server.URL}, opts)
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %s: %s", apiErr.Message, apiErr.Details)
+	}
+
+	if page.Phases.TLSHandshake <= 0 {
+		t.Errorf("expected a TLS handshake time, got %v", page.Phases.TLSHandshake)
+	}
+}
+
+func TestAnalyzeHandlerReportsLoadTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(slowPageHandler))
+	defer server.Close()
+
+	rr := runPipeline(t, map[string]interface{}{"url": server.URL})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"load_time":{"dns_lookup_ms":`) {
+		t.Errorf("expected a load_time breakdown in the response, got %s", rr.Body.String())
+	}
+
+	inline := postAnalyze(t, map[string]string{"html": inlineTestHTML})
+	if strings.Contains(inline.Body.String(), `"load_time"`) {
+		t.Errorf("inline analysis should not report load times, got %s", inline.Body.String())
+	}
+}
+
+func TestFetchTracerReset(t *testing.T) {
+	tr := &fetchTracer{}
+	trace := tr.clientTrace()
+	trace.WroteRequest(httptrace.WroteRequestInfo{})
+	trace.GotFirstResponseByte()
+	tr.setTransfer(time.Second)
+
+	tr.reset()
+	if phases := tr.Phases(); phases != (fetchPhases{}) {
+		t.Errorf("expected reset to clear timings, got %+v", phases)
+	}
+
+	// A done callback without a matching start records nothing
+	trace.GotFirstResponseByte()
+	if phases := tr.Phases(); phases.Server != 0 {
+		t.Errorf("expected no server time without a written request, got %v", phases.Server)
+	}
+}
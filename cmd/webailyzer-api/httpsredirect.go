@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// httpsRedirectTimeoutWarning is added when the HTTPS redirect probe is cut off
+const httpsRedirectTimeoutWarning = "The HTTPS redirect probe did not finish within its time limit, so the plain HTTP site was not checked"
+
+// HTTPSRedirectCheck is the outcome of requesting the plain HTTP version of a site
+type HTTPSRedirectCheck struct {
+	// URL is the plain HTTP URL that was requested
+	URL string `json:"url"`
+	// Redirects is set when the site answered with a redirect to an https:// URL
+	Redirects  bool   `json:"redirects_to_https"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Location   string `json:"location,omitempty"`
+}
+
+// plainHTTPURL returns the root of the plain HTTP site for pageURL. An https:// page is
+// checked on the default HTTP port, since its TLS port says nothing about where plain
+// HTTP is served; an http:// page keeps its port.
+func plainHTTPURL(pageURL string) (string, bool) {
+	parsed, err := url.Parse(pageURL)
+	if err != nil || parsed.Hostname() == "" {
+		return "", false
+	}
+	host := parsed.Host
+	if parsed.Scheme == "https" {
+		host = parsed.Hostname()
+		// IPv6 literals need their brackets back once the port is dropped
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+	}
+	target := url.URL{Scheme: "http", Host: host, Path: "/"}
+	return target.String(), true
+}
+
+// probeHTTPSRedirect requests the plain HTTP version of the site without following
+// redirects and reports whether it redirects to HTTPS. A site that refuses plain HTTP
+// connections serves nothing insecurely and yields no check. The probe is bounded by
+// cfg.Timeout and returns the context error when cut off.
+func probeHTTPSRedirect(ctx context.Context, pageURL string, cfg ProbeConfig) (*HTTPSRedirectCheck, error) {
+	target, ok := plainHTTPURL(pageURL)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", target, nil)
+	if err != nil {
+		return nil, nil
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	resp, err := doProbe(ctx, probeClient(), req, cfg)
+	if err != nil {
+		return nil, ctx.Err()
+	}
+
+	check := &HTTPSRedirectCheck{URL: target, StatusCode: resp.StatusCode}
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if location, err := resp.Location(); err == nil {
+			check.Location = location.String()
+			check.Redirects = location.Scheme == "https"
+		}
+	}
+	return check, nil
+}
+
+// httpsRedirectFindings reports a plain HTTP site that does not send visitors to HTTPS
+func httpsRedirectFindings(check *HTTPSRedirectCheck) []SecurityFinding {
+	if check == nil || check.Redirects {
+		return nil
+	}
+	return []SecurityFinding{{
+		Type:     "missing_https_redirect",
+		Severity: "medium",
+		Subject:  "/",
+		Message:  fmt.Sprintf("%s answered %d instead of redirecting to HTTPS; redirect all plain HTTP requests to https://", check.URL, check.StatusCode),
+	}}
+}
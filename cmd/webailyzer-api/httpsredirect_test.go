@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPlainHTTPURL(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/login?next=/":  "http://example.com/",
+		"https://example.com:8443/":         "http://example.com/",
+		"http://127.0.0.1:8080/page":        "http://127.0.0.1:8080/",
+		"https://[2001:db8::1]:8443/secure": "http://[2001:db8::1]/",
+	}
+	for pageURL, want := range tests {
+		if got, ok := plainHTTPURL(pageURL); !ok || got != want {
+			t.Errorf("plainHTTPURL(%q) = %q, %v; want %q", pageURL, got, ok, want)
+		}
+	}
+	if _, ok := plainHTTPURL("not a url"); ok {
+		t.Error("expected no plain HTTP URL without a host")
+	}
+}
+
+func TestProbeHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		redirects bool
+		finding   bool
+	}{
+		{
+			name: "redirects to https",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "https://"+r.Host+r.URL.Path, http.StatusMovedPermanently)
+			},
+			redirects: true,
+		},
+		{
+			name:    "serves plain http",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			finding: true,
+		},
+		{
+			name: "redirects elsewhere on http",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/home", http.StatusFound)
+			},
+			finding: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			check, err := probeHTTPSRedirect(context.Background(), server.URL+"/page", defaultProbeConfig())
+			if err != nil || check == nil {
+				t.Fatalf("expected a check, got %+v (err %v)", check, err)
+			}
+			if check.Redirects != tt.redirects {
+				t.Errorf("expected redirects_to_https %v, got %+v", tt.redirects, check)
+			}
+			if findings := httpsRedirectFindings(check); hasFinding(findings, "missing_https_redirect") != tt.finding {
+				t.Errorf("expected a missing_https_redirect finding: %v, got %+v", tt.finding, findings)
+			}
+		})
+	}
+}
+
+func TestProbeHTTPSRedirectIgnoresClosedPort(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	target := server.URL
+	server.Close()
+
+	check, err := probeHTTPSRedirect(context.Background(), target, defaultProbeConfig())
+	if check != nil || err != nil {
+		t.Errorf("expected no check for a site without plain HTTP, got %+v (err %v)", check, err)
+	}
+}
+
+func TestAnalyzeHandlerProbesHTTPSRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			http.Redirect(w, r, "https://secure.example/", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Redirects</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	rr := runPipeline(t, map[string]interface{}{"url": server.URL, "probe_https_redirect": true})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.HTTPSRedirect == nil || !response.HTTPSRedirect.Redirects || response.HTTPSRedirect.Location != "https://secure.example/" {
+		t.Errorf("expected the redirect to https to be reported, got %+v", response.HTTPSRedirect)
+	}
+	if hasFinding(response.SecurityFindings, "missing_https_redirect") {
+		t.Errorf("expected no finding for a site that redirects, got %+v", response.SecurityFindings)
+	}
+	if _, ok := response.Timings["https_redirect_probe"]; !ok {
+		t.Errorf("expected an https_redirect_probe timing, got %v", response.Timings)
+	}
+}
+
+func TestAnalyzeHandlerWarnsWhenHTTPSRedirectProbeTimesOut(t *testing.T) {
+	original := appConfig
+	appConfig.Probe.Timeout = 100 * time.Millisecond
+	defer func() { appConfig = original }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(`<html><body>Slow plain HTTP</body></html>`))
+	}))
+	defer server.Close()
+
+	rr := runPipeline(t, map[string]interface{}{"url": server.URL, "probe_https_redirect": true})
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !containsString(response.Warnings, httpsRedirectTimeoutWarning) || response.HTTPSRedirect != nil {
+		t.Errorf("expected a timeout warning and no check, got %v / %+v", response.Warnings, response.HTTPSRedirect)
+	}
+}
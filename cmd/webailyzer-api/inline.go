@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// inlineContentType is reported for pages supplied through the html field
+const inlineContentType = "text/html; charset=utf-8"
+
+// inlineSource returns the page to analyze without fetching when the request
+// supplies HTML directly, either in the html field or as a data: URL.
+// A nil page means the url field must be fetched.
+func inlineSource(req AnalyzeRequest) (*fetchedPage, error) {
+	if req.HTML != "" && req.URL != "" {
+		return nil, fmt.Errorf("provide either url or html, not both")
+	}
+
+	var contentType string
+	var body []byte
+	switch {
+	case req.HTML != "":
+		contentType, body = inlineContentType, []byte(req.HTML)
+	case isDataURL(req.URL):
+		var err error
+		contentType, body, err = decodeDataURL(req.URL)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	if int64(len(body)) > appConfig.MaxBodyBytes {
+		return nil, fmt.Errorf("inline HTML too large (max %d bytes)", appConfig.MaxBodyBytes)
+	}
+
+	// Synthesize the headers a server would have sent so header-based
+	// fingerprints see a plausible response
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", fmt.Sprint(len(body)))
+
+	return &fetchedPage{
+		Header:     header,
+		Body:       body,
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// isDataURL reports whether raw uses the data: scheme
+func isDataURL(raw string) bool {
+	return len(raw) >= 5 && strings.EqualFold(raw[:5], "data:")
+}
+
+// decodeDataURL decodes an RFC 2397 data: URL into its media type and payload
+func decodeDataURL(raw string) (string, []byte, error) {
+	meta, payload, ok := strings.Cut(raw[len("data:"):], ",")
+	if !ok {
+		return "", nil, fmt.Errorf("data URL must contain a comma before the payload")
+	}
+
+	isBase64 := false
+	if trimmed, found := strings.CutSuffix(meta, ";base64"); found {
+		meta, isBase64 = trimmed, true
+	}
+	if meta == "" {
+		meta = "text/plain;charset=US-ASCII"
+	}
+
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("data URL has invalid base64 payload: %v", err)
+		}
+		return meta, data, nil
+	}
+
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("data URL has invalid percent-encoding: %v", err)
+	}
+	return meta, []byte(data), nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const inlineTestHTML = `<html><head><meta name="generator" content="WordPress 6.4"><title>Inline</title></head><body></body></html>`
+
+func postAnalyze(t *testing.T, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	requestBody, _ := json.Marshal(body)
+	req, err := http.NewRequest("POST", "/v1/analyze", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+	return rr
+}
+
+func TestAnalyzeHandlerInlineHTML(t *testing.T) {
+	rr := postAnalyze(t, map[string]string{"html": inlineTestHTML})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if _, ok := response.Detected["PHP"]; !ok {
+		t.Errorf("expected WordPress (and its implied PHP) to be detected in inline HTML, got %v", response.Detected)
+	}
+	if response.ContentType != inlineContentType {
+		t.Errorf("expected synthesized content type %q, got %q", inlineContentType, response.ContentType)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected synthesized status 200, got %d", response.StatusCode)
+	}
+	if response.Redirected || len(response.RedirectHops) != 0 {
+		t.Errorf("inline analysis should not report redirects, got %v", response.RedirectHops)
+	}
+	if _, ok := response.Timings["fetch"]; ok {
+		t.Errorf("inline analysis should not report a fetch timing, got %v", response.Timings)
+	}
+}
+
+func TestAnalyzeHandlerDataURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"percent-encoded", "data:text/html," + url.PathEscape(inlineTestHTML)},
+		{"base64", "data:text/html;base64," + base64.StdEncoding.EncodeToString([]byte(inlineTestHTML))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postAnalyze(t, map[string]string{"url": tt.url})
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			var response AnalyzeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if _, ok := response.Detected["PHP"]; !ok {
+				t.Errorf("expected WordPress (and its implied PHP) to be detected, got %v", response.Detected)
+			}
+			if response.ContentType != "text/html" {
+				t.Errorf("expected content type from data URL, got %q", response.ContentType)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerInlineValidation(t *testing.T) {
+	original := appConfig
+	defer func() { appConfig = original }()
+	appConfig.MaxBodyBytes = 64
+
+	tests := []struct {
+		name    string
+		body    map[string]string
+		details string
+	}{
+		{
+			name:    "both url and html",
+			body:    map[string]string{"url": "https://example.com", "html": "<html></html>"},
+			details: "either url or html",
+		},
+		{
+			name:    "html too large",
+			body:    map[string]string{"html": strings.Repeat("a", 65)},
+			details: "too large",
+		},
+		{
+			name:    "malformed data URL",
+			body:    map[string]string{"url": "data:text/html"},
+			details: "comma",
+		},
+		{
+			name:    "invalid base64",
+			body:    map[string]string{"url": "data:text/html;base64,!!!"},
+			details: "base64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postAnalyze(t, tt.body)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			var response ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if response.Type != ErrorTypeValidation {
+				t.Errorf("expected validation error, got %s", response.Type)
+			}
+			if !strings.Contains(response.Details, tt.details) {
+				t.Errorf("expected details to mention %q, got %q", tt.details, response.Details)
+			}
+		})
+	}
+}
+
+func TestDecodeDataURL(t *testing.T) {
+	mediaType, data, err := decodeDataURL("data:,Hello%2C%20World")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mediaType != "text/plain;charset=US-ASCII" {
+		t.Errorf("expected RFC 2397 default media type, got %q", mediaType)
+	}
+	if string(data) != "Hello, World" {
+		t.Errorf("expected decoded payload, got %q", data)
+	}
+}
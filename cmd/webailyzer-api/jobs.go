@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// jobCleanupInterval is how often finished jobs past their TTL are evicted
+const jobCleanupInterval = time.Minute
+
+// JobStatus is the lifecycle state of an async analysis job
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// errJobStoreFull is returned when the store holds JobStoreMax unfinished jobs
+var errJobStoreFull = errors.New("job store is full")
+
+// Job is an analysis enqueued via POST /v1/jobs and polled via GET /v1/jobs/{id}
+type Job struct {
+	ID          string           `json:"id"`
+	Status      JobStatus        `json:"status"`
+	URL         string           `json:"url,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	Result      *AnalyzeResponse `json:"result,omitempty"`
+	Error       *ErrorResponse   `json:"error,omitempty"`
+}
+
+// finished reports whether the job has reached a final state
+func (j *Job) finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+// jobTask carries what a worker needs to run a queued job
+type jobTask struct {
+	jobID     string
+	requestID string
+	req       AnalyzeRequest
+	opts      ScanOptions
+	page      *fetchedPage
+}
+
+// jobStore holds jobs in memory, bounded by maxJobs. Finished jobs are evicted once
+// older than ttl, or earlier, oldest first, to make room for new jobs.
+type jobStore struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	maxJobs int
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// newJobStore creates an empty store holding at most maxJobs jobs
+func newJobStore(maxJobs int, ttl time.Duration) *jobStore {
+	return &jobStore{
+		jobs:    make(map[string]*Job),
+		maxJobs: maxJobs,
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// add stores a new queued job, evicting finished jobs if the store is full
+func (s *jobStore) add(id, url string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.jobs) >= s.maxJobs {
+		s.evictExpiredLocked()
+	}
+	if len(s.jobs) >= s.maxJobs && !s.evictOldestFinishedLocked() {
+		return Job{}, errJobStoreFull
+	}
+
+	job := &Job{ID: id, Status: JobQueued, URL: url, CreatedAt: s.now().UTC()}
+	s.jobs[id] = job
+	return *job, nil
+}
+
+// get returns a snapshot of the job, if it exists and has not been evicted
+func (s *jobStore) get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// start marks a queued job as running
+func (s *jobStore) start(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		job.Status = JobRunning
+	}
+}
+
+// finish records the outcome of a job; exactly one of result and apiErr is set
+func (s *jobStore) finish(id string, result *AnalyzeResponse, apiErr *APIError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	completed := s.now().UTC()
+	job.CompletedAt = &completed
+	if apiErr != nil {
+		job.Status = JobFailed
+		job.Error = &ErrorResponse{
+			Error:     apiErr.Message,
+			Type:      apiErr.Type,
+			Details:   apiErr.Details,
+			RequestID: apiErr.RequestID,
+			Timestamp: completed.Format(time.RFC3339),
+		}
+		return
+	}
+	job.Status = JobDone
+	job.Result = result
+}
+
+// size returns the number of jobs currently held
+func (s *jobStore) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+// JobStoreStats reports how full the job store is, for monitoring via /health
+type JobStoreStats struct {
+	// Stored counts every job held, queued, running or finished and not yet expired
+	Stored int `json:"stored"`
+	// Pending counts queued and running jobs, which cannot be evicted
+	Pending int `json:"pending"`
+	Max     int `json:"max"`
+}
+
+// stats returns the store's current occupancy
+func (s *jobStore) stats() JobStoreStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := JobStoreStats{Stored: len(s.jobs), Max: s.maxJobs}
+	for _, job := range s.jobs {
+		if !job.finished() {
+			stats.Pending++
+		}
+	}
+	return stats
+}
+
+// evictExpired removes finished jobs completed more than ttl ago and returns how many were removed
+func (s *jobStore) evictExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evictExpiredLocked()
+}
+
+func (s *jobStore) evictExpiredLocked() int {
+	cutoff := s.now().Add(-s.ttl)
+	evicted := 0
+	for id, job := range s.jobs {
+		if job.finished() && job.CompletedAt.Before(cutoff) {
+			delete(s.jobs, id)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// evictOldestFinishedLocked removes the finished job completed longest ago, reporting
+// false when every job is still queued or running
+func (s *jobStore) evictOldestFinishedLocked() bool {
+	var oldest *Job
+	for _, job := range s.jobs {
+		if job.finished() && (oldest == nil || job.CompletedAt.Before(*oldest.CompletedAt)) {
+			oldest = job
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(s.jobs, oldest.ID)
+	return true
+}
+
+// startCleanup evicts expired jobs every interval until the returned stop function is called
+func (s *jobStore) startCleanup(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if evicted := s.evictExpired(); evicted > 0 {
+					logger.WithFields(logrus.Fields{
+						"evicted": evicted,
+						"jobs":    s.size(),
+					}).Debug("Evicted expired jobs")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// jobQueue runs queued jobs on a fixed pool of workers
+type jobQueue struct {
+	store *jobStore
+	tasks chan jobTask
+}
+
+// newJobQueue creates a queue that can hold every job the store can; call start to run workers
+func newJobQueue(store *jobStore) *jobQueue {
+	return &jobQueue{store: store, tasks: make(chan jobTask, store.maxJobs)}
+}
+
+// start launches the workers
+func (q *jobQueue) start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range q.tasks {
+				q.run(task)
+			}
+		}()
+	}
+}
+
+// enqueue adds a job to the store and queues it for a worker
+func (q *jobQueue) enqueue(task jobTask) (Job, error) {
+	id, err := newAnalysisID()
+	if err != nil {
+		return Job{}, err
+	}
+	job, err := q.store.add(id, task.req.URL)
+	if err != nil {
+		return Job{}, err
+	}
+	task.jobID = id
+
+	// The store never holds more unfinished jobs than the channel can buffer
+	q.tasks <- task
+	return job, nil
+}
+
+// run analyzes a single job, with a budget detached from the enqueuing request
+func (q *jobQueue) run(task jobTask) {
+	q.store.start(task.jobID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), task.opts.Timeout)
+	defer cancel()
+
+	result, apiErr := runAnalysis(ctx, task.requestID, task.req, task.opts, task.page)
+	q.store.finish(task.jobID, result, apiErr)
+
+	if task.req.CallbackURL != "" {
+		// Deliver in the background so a slow receiver does not hold up the worker
+		go deliverCallback(task.requestID, task.jobID, task.req.CallbackURL, appConfig.CallbackSecret, result, apiErr)
+	}
+
+	fields := logrus.Fields{
+		"request_id": task.requestID,
+		"job_id":     task.jobID,
+		"url":        task.req.URL,
+	}
+	if apiErr != nil {
+		fields["error"] = apiErr.Details
+		logger.WithFields(fields).Warn("Analysis job failed")
+		return
+	}
+	logger.WithFields(fields).Info("Analysis job completed")
+}
+
+// jobs runs async analyses; set up by main() from the configuration
+var jobs *jobQueue
+
+// createJobHandler handles POST /v1/jobs, accepting the same body as POST /v1/analyze.
+// It validates the request, enqueues it and returns 202 Accepted with the job's location.
+func createJobHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := ""
+	if id := r.Context().Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	var req AnalyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid JSON format",
+			Details:    "Request body must be valid JSON",
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	// Jobs may take longer than synchronous analyses
+	page, opts, apiErr := prepareAnalysis(requestID, req, appConfig.JobTimeout)
+	if apiErr != nil {
+		sendErrorResponse(w, *apiErr)
+		return
+	}
+
+	enqueueJob(w, requestID, req, opts, page)
+}
+
+// enqueueJob queues a validated analysis and responds with 202 Accepted and the job's location
+func enqueueJob(w http.ResponseWriter, requestID string, req AnalyzeRequest, opts ScanOptions, page *fetchedPage) {
+	job, err := jobs.enqueue(jobTask{requestID: requestID, req: req, opts: opts, page: page})
+	if errors.Is(err, errJobStoreFull) {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeRateLimited,
+			Message:    "Too many pending jobs",
+			Details:    fmt.Sprintf("All %d job slots are queued or running; retry later", appConfig.JobStoreMax),
+			StatusCode: http.StatusServiceUnavailable,
+			RequestID:  requestID,
+		})
+		return
+	}
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to enqueue analysis job")
+
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeInternal,
+			Message:    "Failed to enqueue job",
+			Details:    "Error occurred while creating the job",
+			StatusCode: http.StatusInternalServerError,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"job_id":     job.ID,
+		"url":        req.URL,
+		"profile":    opts.Profile,
+	}).Info("Analysis job enqueued")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/v1/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to encode job response")
+	}
+}
+
+// getJobHandler handles GET /v1/jobs/{id}, returning the job's status and, once
+// finished, its result or error
+func getJobHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := ""
+	if id := r.Context().Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	jobID := mux.Vars(r)["id"]
+	job, ok := jobs.store.get(jobID)
+	if !ok {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeNotFound,
+			Message:    "Job not found",
+			Details:    fmt.Sprintf("No job %q; finished jobs expire after %v", jobID, appConfig.JobTTL),
+			StatusCode: http.StatusNotFound,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to encode job response")
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// withJobQueue replaces the global job queue with one running the given number of workers
+func withJobQueue(t *testing.T, maxJobs, workers int) *jobQueue {
+	t.Helper()
+	original := jobs
+	jobs = newJobQueue(newJobStore(maxJobs, time.Hour))
+	jobs.start(workers)
+	t.Cleanup(func() { jobs = original })
+	return jobs
+}
+
+func serveJobs(t *testing.T, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/jobs", createJobHandler).Methods("POST")
+	r.HandleFunc("/v1/jobs/{id}", getJobHandler).Methods("GET")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+// pollJob fetches the job until it finishes or ten seconds pass
+func pollJob(t *testing.T, location string) Job {
+	t.Helper()
+	return pollJobWithin(t, location, 10*time.Second)
+}
+
+// pollJobWithin fetches the job until it finishes or the timeout passes
+func pollJobWithin(t *testing.T, location string, timeout time.Duration) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		rr := serveJobs(t, "GET", location, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 polling %s, got %d: %s", location, rr.Code, rr.Body.String())
+		}
+		var job Job
+		if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		if job.finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish in time, last status %q", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobEnqueueAndPollUntilDone(t *testing.T) {
+	withJobQueue(t, 10, 1)
+	server := newFixtureServer(t)
+
+	rr := serveJobs(t, "POST", "/v1/jobs", `{"url": "`+server.URL+`/wordpress"}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var queued Job
+	if err := json.Unmarshal(rr.Body.Bytes(), &queued); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	if queued.ID == "" || queued.Result != nil {
+		t.Errorf("expected a new job without a result, got %+v", queued)
+	}
+	location := rr.Header().Get("Location")
+	if location != "/v1/jobs/"+queued.ID {
+		t.Fatalf("expected Location /v1/jobs/%s, got %q", queued.ID, location)
+	}
+
+	job := pollJob(t, location)
+	if job.Status != JobDone {
+		t.Fatalf("expected job to be done, got %q with error %+v", job.Status, job.Error)
+	}
+	if job.Result == nil || !hasTechnology(job.Result.Detected, "WordPress") {
+		t.Errorf("expected the result to detect WordPress, got %+v", job.Result)
+	}
+	if job.CompletedAt == nil {
+		t.Error("expected a completion time on a finished job")
+	}
+}
+
+func TestJobOutlastsSlowUpstream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for a 12s upstream")
+	}
+	withJobQueue(t, 10, 1)
+
+	// Slower than the old fixed 10s header and 15s client timeouts, well within JOB_TIMEOUT
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(12 * time.Second):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="generator" content="WordPress 6.4.2"></head><body>Slow</body></html>`))
+	}))
+	t.Cleanup(server.Close)
+
+	rr := serveJobs(t, "POST", "/v1/jobs", `{"url": "`+server.URL+`"}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	job := pollJobWithin(t, rr.Header().Get("Location"), 30*time.Second)
+	if job.Status != JobDone {
+		t.Fatalf("expected job to be done, got %q with error %+v", job.Status, job.Error)
+	}
+	if !hasTechnology(job.Result.Detected, "WordPress") {
+		t.Errorf("expected the result to detect WordPress, got %+v", job.Result.Detected)
+	}
+}
+
+func TestJobRecordsFailure(t *testing.T) {
+	withJobQueue(t, 10, 1)
+	server := httptest.NewServer(http.NotFoundHandler())
+	target := server.URL
+	server.Close()
+
+	rr := serveJobs(t, "POST", "/v1/jobs", `{"url": "`+target+`"}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	job := pollJob(t, rr.Header().Get("Location"))
+	if job.Status != JobFailed || job.Error == nil {
+		t.Fatalf("expected a failed job with an error, got %+v", job)
+	}
+	if job.Error.Type != ErrorTypeNetwork {
+		t.Errorf("expected a network error, got %q", job.Error.Type)
+	}
+}
+
+func TestJobNotFound(t *testing.T) {
+	withJobQueue(t, 10, 1)
+
+	rr := serveJobs(t, "GET", "/v1/jobs/0123456789abcdef0123456789abcdef", "")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), string(ErrorTypeNotFound)) {
+		t.Errorf("expected a not found error, got %s", rr.Body.String())
+	}
+}
+
+func TestCreateJobValidatesRequest(t *testing.T) {
+	withJobQueue(t, 10, 1)
+
+	for _, body := range []string{`not json`, `{"url": "ftp://example.com"}`, `{"url": "https://example.com", "profile": "missing"}`} {
+		rr := serveJobs(t, "POST", "/v1/jobs", body)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, rr.Code)
+		}
+	}
+	if n := jobs.store.size(); n != 0 {
+		t.Errorf("expected invalid requests not to create jobs, got %d", n)
+	}
+}
+
+func TestCreateJobRejectsWhenFull(t *testing.T) {
+	// No workers, so the first job stays queued
+	withJobQueue(t, 1, 0)
+
+	body := `{"html": "<html><head><title>Inline</title></head></html>"}`
+	if rr := serveJobs(t, "POST", "/v1/jobs", body); rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr := serveJobs(t, "POST", "/v1/jobs", body)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 with every slot pending, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestJobStoreEviction(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newJobStore(2, time.Hour)
+	store.now = func() time.Time { return now }
+
+	store.add("old", "https://old.example")
+	store.finish("old", &AnalyzeResponse{}, nil)
+	now = now.Add(time.Minute)
+	store.add("pending", "https://pending.example")
+
+	// A full store drops its oldest finished job for a new one
+	if _, err := store.add("new", "https://new.example"); err != nil {
+		t.Fatalf("expected the finished job to make room, got %v", err)
+	}
+	if _, ok := store.get("old"); ok {
+		t.Error("expected the oldest finished job to be evicted")
+	}
+
+	// Unfinished jobs are never evicted
+	if _, err := store.add("another", "https://another.example"); err != errJobStoreFull {
+		t.Errorf("expected errJobStoreFull, got %v", err)
+	}
+
+	// Finished jobs expire once the TTL passes
+	store.finish("new", &AnalyzeResponse{}, nil)
+	now = now.Add(59 * time.Minute)
+	if evicted := store.evictExpired(); evicted != 0 {
+		t.Errorf("expected nothing to expire before the TTL, evicted %d", evicted)
+	}
+	now = now.Add(2 * time.Minute)
+	if evicted := store.evictExpired(); evicted != 1 {
+		t.Errorf("expected the finished job to expire, evicted %d", evicted)
+	}
+	if _, ok := store.get("pending"); !ok {
+		t.Error("expected the pending job to survive expiry")
+	}
+}
+
+func TestJobStoreCleanup(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newJobStore(10, time.Hour)
+	store.now = func() time.Time { return now }
+	store.add("done", "https://done.example")
+	store.finish("done", &AnalyzeResponse{}, nil)
+	// Advance the clock before the cleanup goroutine reads it
+	now = now.Add(2 * time.Hour)
+
+	stop := store.startCleanup(time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for store.size() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the cleanup goroutine to evict the expired job")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Stopping is idempotent
+	stop()
+}
+
+func TestHealthReportsJobStoreSize(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newJobStore(10, time.Hour)
+	store.now = func() time.Time { return now }
+
+	original := jobs
+	jobs = newJobQueue(store)
+	t.Cleanup(func() { jobs = original })
+
+	health := func() JobStoreStats {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		healthHandler(rr, httptest.NewRequest("GET", "/health", nil))
+		var response HealthResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode health response: %v", err)
+		}
+		if response.Jobs == nil {
+			t.Fatalf("expected job store stats in %s", rr.Body.String())
+		}
+		return *response.Jobs
+	}
+
+	store.add("done", "https://done.example")
+	store.finish("done", &AnalyzeResponse{}, nil)
+	store.add("pending", "https://pending.example")
+	if stats := health(); stats != (JobStoreStats{Stored: 2, Pending: 1, Max: 10}) {
+		t.Errorf("expected 2 stored jobs with 1 pending, got %+v", stats)
+	}
+
+	now = now.Add(2 * time.Hour)
+	store.evictExpired()
+	if stats := health(); stats != (JobStoreStats{Stored: 1, Pending: 1, Max: 10}) {
+		t.Errorf("expected the expired job to leave the count, got %+v", stats)
+	}
+}
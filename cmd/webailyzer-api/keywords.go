@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// keywordTopTerms is the number of most frequent terms reported
+	keywordTopTerms = 10
+	// keywordStuffingMinWords is the fewest words a page needs before stuffing is
+	// flagged; on shorter pages a single repeated term is naturally dense
+	keywordStuffingMinWords = 100
+)
+
+// KeywordReport summarizes the most frequent terms in a page's visible text
+type KeywordReport struct {
+	// WordCount counts the visible words, stop words included
+	WordCount int `json:"word_count"`
+	// ReadingTimeMinutes is rounded up, so only a page without words reads in 0 minutes
+	ReadingTimeMinutes int           `json:"reading_time_minutes"`
+	TopTerms           []TermDensity `json:"top_terms"`
+	// Stuffing is set when a term's density exceeds KEYWORD_STUFFING_THRESHOLD
+	Stuffing      bool     `json:"keyword_stuffing"`
+	StuffingTerms []string `json:"stuffing_terms,omitempty"`
+}
+
+// TermDensity is how often a term occurs, as a count and a percentage of WordCount
+type TermDensity struct {
+	Term    string  `json:"term"`
+	Count   int     `json:"count"`
+	Density float64 `json:"density_percent"`
+}
+
+// stopWords are common English words left out of the term counts
+var stopWords = toSet([]string{
+	"a", "about", "after", "all", "also", "an", "and", "any", "are", "as", "at",
+	"be", "been", "but", "by", "can", "could", "do", "does", "for", "from", "has",
+	"have", "he", "her", "his", "how", "i", "if", "in", "into", "is", "it", "its",
+	"just", "more", "most", "my", "no", "not", "of", "on", "or", "our", "out", "she",
+	"so", "some", "than", "that", "the", "their", "them", "then", "there", "these",
+	"they", "this", "to", "up", "us", "was", "we", "were", "what", "when", "which",
+	"who", "will", "with", "would", "you", "your",
+})
+
+// toSet returns the values as a lookup set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// analyzeKeywords counts the terms in the page's visible text and flags keyword
+// stuffing when any term's density exceeds threshold percent. Stop words count toward
+// the word total but are not reported as terms. Reading time assumes wordsPerMinute.
+func analyzeKeywords(body []byte, threshold float64, wordsPerMinute int) *KeywordReport {
+	_, text := pageText(body)
+
+	report := &KeywordReport{TopTerms: []TermDensity{}}
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		word = strings.Trim(word, "'")
+		if word == "" {
+			continue
+		}
+		report.WordCount++
+		if len([]rune(word)) < 2 || stopWords[word] || isNumber(word) {
+			continue
+		}
+		counts[word]++
+	}
+	if report.WordCount == 0 {
+		return report
+	}
+	report.ReadingTimeMinutes = readingTime(report.WordCount, wordsPerMinute)
+
+	terms := make([]TermDensity, 0, len(counts))
+	for term, count := range counts {
+		terms = append(terms, TermDensity{
+			Term:    term,
+			Count:   count,
+			Density: math.Round(float64(count)*10000/float64(report.WordCount)) / 100,
+		})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Term < terms[j].Term
+	})
+
+	if report.WordCount >= keywordStuffingMinWords {
+		for _, t := range terms {
+			if float64(t.Count)*100/float64(report.WordCount) <= threshold {
+				break
+			}
+			report.StuffingTerms = append(report.StuffingTerms, t.Term)
+		}
+		report.Stuffing = len(report.StuffingTerms) > 0
+	}
+
+	if len(terms) > keywordTopTerms {
+		terms = terms[:keywordTopTerms]
+	}
+	report.TopTerms = terms
+	return report
+}
+
+// readingTime returns the minutes needed to read words at wordsPerMinute, rounded up
+func readingTime(words, wordsPerMinute int) int {
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// isNumber reports whether word is made up of digits only
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
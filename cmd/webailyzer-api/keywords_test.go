@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// bakeryProse is 131 words of ordinary copy in which no term exceeds 4%
+const bakeryProse = `Our corner bakery opens at six every morning. We bake sourdough loaves, rye bread and seeded rolls in a stone oven that has warmed this street for forty years. Pastries come out next: butter croissants, almond twists and cinnamon buns glazed while still hot. Coffee is roasted by a small farm cooperative in the hills and brewed in batches so nothing sits for long. Regulars know that Saturday brings fruit tarts, which usually sell out before noon. Families stop in after school for cookies, and the night shift from the hospital grabs sandwiches on their way home. Ask about catering for weddings, birthdays or office breakfasts. Gluten free options rotate weekly and the staff can tell you exactly which flour went into each batch. Thank you for supporting a neighborhood bakery.`
+
+// proseHTML builds a page from bakeryProse with extra appended to the body, plus a
+// script whose text must not be counted
+func proseHTML(extra string) []byte {
+	return []byte(fmt.Sprintf(`<html><head><title>Corner Bakery</title><script>var shoes = "shoes shoes shoes";</script></head><body><p>%s</p><p>%s</p></body></html>`, bakeryProse, extra))
+}
+
+func TestAnalyzeKeywords(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		stuffing []string
+	}{
+		{name: "empty page", body: []byte(`<html><body></body></html>`)},
+		{name: "normal prose", body: proseHTML("")},
+		{name: "short page repeating a term", body: []byte(`<p>shoes shoes shoes cheap shoes</p>`)},
+		{name: "stuffed page", body: proseHTML(strings.Repeat("cheap shoes ", 10)), stuffing: []string{"cheap", "shoes"}},
+		{name: "stuffing inside a script", body: proseHTML(`<script>` + strings.Repeat("cheap shoes ", 10) + `</script>`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeKeywords(tt.body, 4, 200)
+			if report.Stuffing != (len(tt.stuffing) > 0) || strings.Join(report.StuffingTerms, ",") != strings.Join(tt.stuffing, ",") {
+				t.Errorf("expected stuffing terms %v, got %v (stuffing=%v)", tt.stuffing, report.StuffingTerms, report.Stuffing)
+			}
+		})
+	}
+}
+
+func TestAnalyzeKeywordsCountsTerms(t *testing.T) {
+	report := analyzeKeywords(proseHTML(""), 4, 200)
+
+	// The title adds two words to the prose
+	if report.WordCount != 133 {
+		t.Errorf("expected 133 words, got %d", report.WordCount)
+	}
+	if len(report.TopTerms) != keywordTopTerms {
+		t.Fatalf("expected %d top terms, got %+v", keywordTopTerms, report.TopTerms)
+	}
+	for _, term := range report.TopTerms {
+		if stopWords[term.Term] {
+			t.Errorf("stop word %q should not be reported", term.Term)
+		}
+		if term.Term == "shoes" {
+			t.Error("script text should not be counted")
+		}
+	}
+	// bakery also appears in the title; ties after it are broken alphabetically
+	if top := report.TopTerms[0]; top.Term != "bakery" || top.Count != 3 || top.Density != 2.26 {
+		t.Errorf("expected bakery 3 times at 2.26%%, got %+v", top)
+	}
+	if second := report.TopTerms[1]; second.Term != "corner" || second.Count != 2 {
+		t.Errorf("expected corner twice, got %+v", second)
+	}
+}
+
+func TestAnalyzeKeywordsIgnoresHiddenText(t *testing.T) {
+	code := strings.Repeat("function track(event) { send(event) } ", 50)
+	body := proseHTML(`<script>` + code + `</script><style>` + strings.Repeat(".hero { color: red } ", 50) +
+		`</style><noscript>` + strings.Repeat("enable javascript ", 50) + `</noscript>`)
+
+	report := analyzeKeywords(body, 4, 200)
+	if report.WordCount != 133 {
+		t.Errorf("expected script, style and noscript text to be left out of 133 words, got %d", report.WordCount)
+	}
+	for _, term := range report.TopTerms {
+		if containsString([]string{"function", "track", "event", "send", "hero", "color", "red", "enable", "javascript"}, term.Term) {
+			t.Errorf("hidden text term %q should not be counted", term.Term)
+		}
+	}
+}
+
+func TestAnalyzeKeywordsReadingTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		words int
+		want  int
+	}{
+		{name: "empty page", words: 0, want: 0},
+		{name: "50 words", words: 50, want: 1},
+		{name: "exactly one minute", words: 200, want: 1},
+		{name: "just over a minute", words: 201, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte("<p>" + strings.Repeat("word ", tt.words) + "</p>")
+			if got := analyzeKeywords(body, 4, 200).ReadingTimeMinutes; got != tt.want {
+				t.Errorf("expected %d minutes for %d words, got %d", tt.want, tt.words, got)
+			}
+		})
+	}
+}
+
+func TestAnalyzeKeywordsReadingSpeed(t *testing.T) {
+	body := []byte("<p>" + strings.Repeat("word ", 250) + "</p>")
+	if got := analyzeKeywords(body, 4, 100).ReadingTimeMinutes; got != 3 {
+		t.Errorf("expected 250 words at 100 per minute to take 3 minutes, got %d", got)
+	}
+	if got := analyzeKeywords(body, 4, 300).ReadingTimeMinutes; got != 1 {
+		t.Errorf("expected 250 words at 300 per minute to take 1 minute, got %d", got)
+	}
+}
+
+func TestAnalyzeKeywordsThreshold(t *testing.T) {
+	body := proseHTML(strings.Repeat("shoes ", 5))
+	if report := analyzeKeywords(body, 4, 200); report.Stuffing {
+		t.Errorf("expected 5 of 138 words to stay under 4%%, got %v", report.StuffingTerms)
+	}
+	if report := analyzeKeywords(body, 3, 200); !containsString(report.StuffingTerms, "shoes") {
+		t.Errorf("expected shoes to exceed a 3%% threshold, got %v", report.StuffingTerms)
+	}
+}
+
+func TestAnalyzeHandlerReportsKeywords(t *testing.T) {
+	rr := runPipeline(t, map[string]interface{}{
+		"html":      string(proseHTML(strings.Repeat("cheap shoes ", 10))),
+		"analyzers": []string{"keywords"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"keyword_stuffing":true`) {
+		t.Errorf("expected keyword stuffing in the response, got %s", rr.Body.String())
+	}
+}
+
+func TestAnalyzeHandlerUsesConfiguredReadingSpeed(t *testing.T) {
+	original := appConfig
+	appConfig.ReadingWordsPerMinute = 50
+	defer func() { appConfig = original }()
+
+	rr := runPipeline(t, map[string]interface{}{"html": string(proseHTML("")), "analyzers": []string{"keywords"}})
+	// 133 words at 50 words per minute
+	if !strings.Contains(rr.Body.String(), `"reading_time_minutes":3`) {
+		t.Errorf("expected a 3 minute reading time, got %s", rr.Body.String())
+	}
+}
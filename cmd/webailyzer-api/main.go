@@ -1,8 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net"
@@ -10,11 +15,16 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"regexp"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -25,10 +35,84 @@ import (
 // Logger instance for structured logging
 var logger = logrus.New()
 
+var (
+	minTLSVersion = flag.String("min-tls-version", "", "Minimum TLS version for outbound requests: 1.0, 1.1, 1.2 or 1.3 (default: Go default)")
+)
+
+// outboundMinTLSVersion is the parsed -min-tls-version value; zero keeps the Go default
+var outboundMinTLSVersion uint16
+
 func main() {
+	flag.Parse()
+
 	// Initialize logger
 	initLogger()
 
+	version, err := parseTLSVersion(*minTLSVersion)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid -min-tls-version")
+	}
+	outboundMinTLSVersion = version
+
+	// Load deployment configuration from the environment
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid configuration")
+	}
+	appConfig = cfg
+	requestLogSampler = newLogSampler(appConfig.LogSampleRate)
+
+	if appConfig.ScanProfilesFile != "" {
+		profiles, err := loadScanProfiles(appConfig.ScanProfilesFile)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid scan profiles")
+		}
+		scanProfiles = profiles
+	}
+
+	if appConfig.TrackersFile != "" || appConfig.ConsentSignaturesFile != "" {
+		trackers, consent := defaultTrackerSignatures(), defaultConsentSignatures()
+		var err error
+		if appConfig.TrackersFile != "" {
+			if trackers, err = loadTrackerSignatures(appConfig.TrackersFile); err != nil {
+				logger.WithError(err).Fatal("Invalid tracker list")
+			}
+		}
+		if appConfig.ConsentSignaturesFile != "" {
+			if consent, err = loadConsentSignatures(appConfig.ConsentSignaturesFile); err != nil {
+				logger.WithError(err).Fatal("Invalid consent signatures")
+			}
+		}
+		privacyAnalyzer = NewPrivacyAnalyzer(trackers, consent)
+	}
+
+	if appConfig.Soft404PatternsFile != "" {
+		patterns, err := loadSoft404Patterns(appConfig.Soft404PatternsFile)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid soft 404 patterns")
+		}
+		soft404Patterns = patterns
+	}
+
+	if appConfig.PageStoreDir != "" {
+		store, err := newDirBlobStore(appConfig.PageStoreDir)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid page store")
+		}
+		pageStore = store
+	}
+
+	if appConfig.ProbeCacheSize > 0 {
+		probeResults = newProbeCache(appConfig.ProbeCacheSize, appConfig.ProbeCacheTTL)
+	}
+
+	if appConfig.FetchProxy != "" {
+		proxyURL, _ := parseProxyURL(appConfig.FetchProxy)
+		logger.WithField("proxy", proxyURL.Redacted()).Info("Outbound fetches routed through FETCH_PROXY")
+	} else {
+		logger.Info("Outbound fetches use HTTP_PROXY/HTTPS_PROXY/NO_PROXY from the environment, if set")
+	}
+
 	// Optimize garbage collector settings
 	optimizeGCSettings()
 
@@ -38,13 +122,21 @@ func main() {
 	// Start memory monitoring
 	startMemoryMonitoring()
 
+	// Start the async job workers and evict finished jobs once they expire
+	jobs = newJobQueue(newJobStore(appConfig.JobStoreMax, appConfig.JobTTL))
+	jobs.start(appConfig.JobWorkers)
+	stopJobCleanup := jobs.store.startCleanup(jobCleanupInterval)
+
 	// Create router
 	r := mux.NewRouter()
 
 	// Add error handling middleware
 	r.Use(errorHandlingMiddleware)
 	r.Use(loggingMiddleware)
+	r.Use(newIPConcurrencyLimiter(appConfig.MaxConcurrentPerIP).Middleware)
 	r.Use(timeoutMiddleware)
+	// Inside the timeout, so a gzip writer is only released once its handler returns
+	r.Use(compressionMiddleware)
 
 	// Add CORS middleware
 	corsHandler := handlers.CORS(
@@ -55,20 +147,27 @@ func main() {
 
 	// Register routes
 	r.HandleFunc("/health", healthHandler).Methods("GET")
+	r.HandleFunc("/ready", readyHandler).Methods("GET")
+	r.HandleFunc("/version", versionHandler).Methods("GET")
 	r.HandleFunc("/v1/analyze", analyzeHandler).Methods("POST")
+	r.HandleFunc("/v1/analyze/compare", compareHandler).Methods("POST")
+	r.HandleFunc("/v1/analyses/{id}/reanalyze", reanalyzeHandler).Methods("POST")
+	r.HandleFunc("/v1/jobs", createJobHandler).Methods("POST")
+	r.HandleFunc("/v1/jobs/{id}", getJobHandler).Methods("GET")
+	r.HandleFunc("/v1/categories", categoriesHandler).Methods("GET")
 
 	// Create server with appropriate timeouts
 	srv := &http.Server{
-		Addr:         ":8080",
+		Addr:         ":" + appConfig.Port,
 		Handler:      corsHandler,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		ReadTimeout:  appConfig.ReadTimeout,
+		WriteTimeout: appConfig.WriteTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
 
 	// Start server in a goroutine
 	go func() {
-		logger.Info("Starting WebAIlyzer Lite API server on port 8080")
+		logger.Infof("Starting WebAIlyzer Lite API server on port %s", appConfig.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.WithError(err).Fatal("Server failed to start")
 		}
@@ -90,18 +189,21 @@ func main() {
 	} else {
 		logger.Info("Server shutdown complete")
 	}
+	stopJobCleanup()
 }
 
 // Error types for structured error handling
 type ErrorType string
 
 const (
-	ErrorTypeValidation    ErrorType = "validation_error"
-	ErrorTypeNetwork       ErrorType = "network_error"
-	ErrorTypeTimeout       ErrorType = "timeout_error"
-	ErrorTypeInternal      ErrorType = "internal_error"
-	ErrorTypeNotFound      ErrorType = "not_found_error"
-	ErrorTypeUnauthorized  ErrorType = "unauthorized_error"
+	ErrorTypeValidation       ErrorType = "validation_error"
+	ErrorTypeNetwork          ErrorType = "network_error"
+	ErrorTypeTimeout          ErrorType = "timeout_error"
+	ErrorTypeInternal         ErrorType = "internal_error"
+	ErrorTypeNotFound         ErrorType = "not_found_error"
+	ErrorTypeUnauthorized     ErrorType = "unauthorized_error"
+	ErrorTypeRateLimited      ErrorType = "rate_limit_error"
+	ErrorTypeTooManyRedirects ErrorType = "too_many_redirects_error"
 )
 
 // APIError represents a structured API error
@@ -120,8 +222,9 @@ func (e APIError) Error() string {
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status string      `json:"status"`
-	Memory MemoryStats `json:"memory,omitempty"`
+	Status string         `json:"status"`
+	Memory MemoryStats    `json:"memory,omitempty"`
+	Jobs   *JobStoreStats `json:"jobs,omitempty"`
 }
 
 // healthHandler handles GET /health requests
@@ -138,6 +241,10 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		Status: "ok",
 		Memory: getMemoryStats(),
 	}
+	if jobs != nil {
+		stats := jobs.store.stats()
+		response.Jobs = &stats
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if requestID != "" {
 		w.Header().Set("X-Request-ID", requestID)
@@ -159,9 +266,97 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Shared wappalyzer engine, compiled once and reused across requests
+var (
+	wappalyzerOnce   sync.Once
+	wappalyzerEngine *wappalyzer.Wappalyze
+	wappalyzerErr    error
+)
+
+// getWappalyzer returns the shared wappalyzer engine, initializing it on first use
+func getWappalyzer() (*wappalyzer.Wappalyze, error) {
+	wappalyzerOnce.Do(func() {
+		wappalyzerEngine, wappalyzerErr = wappalyzer.New()
+	})
+	return wappalyzerEngine, wappalyzerErr
+}
+
+// Category describes a wappalyzer technology category
+type Category struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+// CategoriesResponse represents the categories listing response
+type CategoriesResponse struct {
+	Categories []Category `json:"categories"`
+}
+
+// categoriesHandler handles GET /v1/categories requests
+func categoriesHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := ""
+	if id := r.Context().Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	if _, err := getWappalyzer(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Wappalyzer initialization failed")
+
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeInternal,
+			Message:    "Technology detection engine failed",
+			Details:    "Unable to initialize the technology detection engine",
+			StatusCode: http.StatusInternalServerError,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	mapping := wappalyzer.GetCategoriesMapping()
+	response := CategoriesResponse{Categories: make([]Category, 0, len(mapping))}
+	for id, category := range mapping {
+		response.Categories = append(response.Categories, Category{
+			ID:       id,
+			Name:     category.Name,
+			Priority: category.Priority,
+		})
+	}
+	sort.Slice(response.Categories, func(i, j int) bool {
+		return response.Categories[i].ID < response.Categories[j].ID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to encode categories response")
+	}
+}
+
 // AnalyzeRequest represents the request structure for analysis
 type AnalyzeRequest struct {
-	URL string `json:"url"`
+	URL            string `json:"url"`
+	HTML           string `json:"html,omitempty"`
+	Profile        string `json:"profile,omitempty"`
+	UserAgent      string `json:"user_agent,omitempty"`
+	TimeoutMs      int    `json:"timeout_ms,omitempty"`
+	IncludeHeaders *bool  `json:"include_headers,omitempty"`
+	// ProbeSensitivePaths opts in to HEAD requests for commonly exposed files on the site
+	ProbeSensitivePaths *bool `json:"probe_sensitive_paths,omitempty"`
+	// ProbeCORS opts in to a second request with an untrusted Origin to detect origin reflection
+	ProbeCORS *bool `json:"probe_cors,omitempty"`
+	// ProbeHTTPSRedirect opts in to requesting the plain HTTP site to check it redirects to HTTPS
+	ProbeHTTPSRedirect *bool `json:"probe_https_redirect,omitempty"`
+	// Analyzers limits the analysis to the named analyzers; all run when omitted
+	Analyzers []string `json:"analyzers,omitempty"`
+	// CallbackURL, when set, makes the analysis run in the background and POSTs the result there
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
 // ErrorResponse represents error response structure
@@ -175,9 +370,86 @@ type ErrorResponse struct {
 
 // AnalyzeResponse represents the analysis response structure
 type AnalyzeResponse struct {
-	URL         string                 `json:"url"`
-	Detected    map[string]interface{} `json:"detected"`
-	ContentType string                 `json:"content_type,omitempty"`
+	URL               string                 `json:"url"`
+	AnalysisID        string                 `json:"analysis_id,omitempty"`
+	Profile           string                 `json:"profile,omitempty"`
+	FinalURL          string                 `json:"final_url,omitempty"`
+	Redirected        bool                   `json:"redirected"`
+	RedirectChain     []string               `json:"redirect_chain,omitempty"`
+	RedirectHops      []RedirectHop          `json:"redirect_hops,omitempty"`
+	Detected          map[string]interface{} `json:"detected"`
+	ContentType       string                 `json:"content_type,omitempty"`
+	StatusCode        int                    `json:"status_code"`
+	ResponseHeaders   map[string]string      `json:"response_headers,omitempty"`
+	Headers           map[string][]string    `json:"headers,omitempty"`
+	SecurityFindings  []SecurityFinding      `json:"security_findings,omitempty"`
+	Cookies           []CookieAnalysis       `json:"cookies,omitempty"`
+	ExposedPaths      []string               `json:"exposed_paths,omitempty"`
+	HTTPSRedirect     *HTTPSRedirectCheck    `json:"https_redirect,omitempty"`
+	Soft404           bool                   `json:"soft_404,omitempty"`
+	Soft404Indicator  string                 `json:"soft_404_indicator,omitempty"`
+	ChallengeDetected bool                   `json:"challenge_detected,omitempty"`
+	ChallengeProvider string                 `json:"challenge_provider,omitempty"`
+	Warnings          []string               `json:"warnings,omitempty"`
+	Privacy           *PrivacyReport         `json:"privacy,omitempty"`
+	Keywords          *KeywordReport         `json:"keywords,omitempty"`
+	Accessibility     *AccessibilityReport   `json:"accessibility,omitempty"`
+	Performance       *PerformanceReport     `json:"performance,omitempty"`
+	SEO               *SEOReport             `json:"seo,omitempty"`
+	Timings           map[string]float64     `json:"timings"`
+	DeadlineMs        int64                  `json:"deadline_ms,omitempty"`
+	LoadTime          *LoadTimeMetrics       `json:"load_time,omitempty"`
+}
+
+// fingerprintHeaders is the allowlist of response headers echoed back to clients
+var fingerprintHeaders = []string{"Server", "X-Powered-By", "Content-Length", "Content-Encoding"}
+
+// selectResponseHeaders returns the allowlisted headers present in the response
+func selectResponseHeaders(header http.Header) map[string]string {
+	selected := make(map[string]string)
+	for _, name := range fingerprintHeaders {
+		if value := header.Get(name); value != "" {
+			selected[name] = value
+		}
+	}
+	return selected
+}
+
+// redactedValue replaces header values that must not be echoed back to clients
+const redactedValue = "[REDACTED]"
+
+// sanitizeHeaders copies all response headers, keeping only the cookie names from Set-Cookie
+func sanitizeHeaders(header http.Header) map[string][]string {
+	sanitized := make(map[string][]string, len(header))
+	for name, values := range header {
+		if http.CanonicalHeaderKey(name) != "Set-Cookie" {
+			sanitized[name] = append([]string(nil), values...)
+			continue
+		}
+		redacted := make([]string, 0, len(values))
+		for _, value := range values {
+			cookieName, _, _ := strings.Cut(value, "=")
+			redacted = append(redacted, strings.TrimSpace(cookieName)+"="+redactedValue)
+		}
+		sanitized[name] = redacted
+	}
+	return sanitized
+}
+
+// RedirectHop describes one URL visited while following redirects
+type RedirectHop struct {
+	URL    string `json:"url"`
+	Scheme string `json:"scheme"`
+}
+
+// SecurityFinding represents a security concern observed during analysis
+type SecurityFinding struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Subject names what the finding is about, such as a header, cookie or path.
+	// Unlike Message it holds no host or value, so findings can be compared across sites.
+	Subject string `json:"subject,omitempty"`
 }
 
 // initLogger initializes the structured logger
@@ -194,11 +466,24 @@ func generateRequestID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
 
+// requestIDPattern limits inbound request IDs to safe characters such as those of
+// UUIDs and numeric IDs, so they can be echoed in headers and logs
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIDFrom reuses a valid inbound X-Request-ID, such as a gateway's correlation
+// ID, and generates a new ID otherwise
+func requestIDFrom(r *http.Request) string {
+	if id := r.Header.Get("X-Request-ID"); requestIDPattern.MatchString(id) {
+		return id
+	}
+	return generateRequestID()
+}
+
 // errorHandlingMiddleware provides consistent error handling across all endpoints
 func errorHandlingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Add request ID to context
-		requestID := generateRequestID()
+		// Add request ID to context, keeping the caller's ID when it sent one
+		requestID := requestIDFrom(r)
 		ctx := context.WithValue(r.Context(), "request_id", requestID)
 		r = r.WithContext(ctx)
 
@@ -236,30 +521,74 @@ func loggingMiddleware(next http.Handler) http.Handler {
 		start := time.Now()
 		requestID := r.Context().Value("request_id").(string)
 
-		logger.WithFields(logrus.Fields{
-			"request_id": requestID,
-			"method":     r.Method,
-			"path":       r.URL.Path,
-			"user_agent": r.UserAgent(),
-			"remote_ip":  getClientIP(r),
-		}).Info("Request started")
+		// Successful requests are logged 1 in LOG_SAMPLE_RATE; failures always are
+		sampled := requestLogSampler.Sample()
+		if sampled {
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"user_agent": r.UserAgent(),
+				"remote_ip":  getClientIP(r),
+			}).Info("Request started")
+		}
 
 		// Wrap response writer to capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		
 		next.ServeHTTP(wrapped, r)
 
+		if !sampled && wrapped.statusCode < 400 {
+			return
+		}
+
 		duration := time.Since(start)
-		logger.WithFields(logrus.Fields{
+		fields := logrus.Fields{
 			"request_id":  requestID,
 			"method":      r.Method,
 			"path":        r.URL.Path,
 			"status_code": wrapped.statusCode,
 			"duration_ms": duration.Milliseconds(),
-		}).Info("Request completed")
+		}
+		if !sampled {
+			// The start line was skipped, so keep its client details here
+			fields["user_agent"] = r.UserAgent()
+			fields["remote_ip"] = getClientIP(r)
+		}
+
+		entry := logger.WithFields(fields)
+		switch {
+		case wrapped.statusCode >= 500:
+			entry.Error("Request completed")
+		case wrapped.statusCode >= 400:
+			entry.Warn("Request completed")
+		default:
+			entry.Info("Request completed")
+		}
 	})
 }
 
+// logSampler selects 1 in rate events; a rate of 1 or less selects every event
+type logSampler struct {
+	rate    uint64
+	counter atomic.Uint64
+}
+
+// requestLogSampler samples request logs, configured at startup from LOG_SAMPLE_RATE
+var requestLogSampler = newLogSampler(1)
+
+func newLogSampler(rate int) *logSampler {
+	if rate < 1 {
+		rate = 1
+	}
+	return &logSampler{rate: uint64(rate)}
+}
+
+// Sample reports whether the next event should be logged
+func (s *logSampler) Sample() bool {
+	return (s.counter.Add(1)-1)%s.rate == 0
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -289,30 +618,132 @@ func getClientIP(r *http.Request) string {
 	return ip
 }
 
+// ipConcurrencyLimiter tracks in-flight requests per client IP
+type ipConcurrencyLimiter struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	limit    int
+}
+
+// newIPConcurrencyLimiter creates a limiter allowing up to limit in-flight requests per IP.
+// A limit of zero or less disables the check.
+func newIPConcurrencyLimiter(limit int) *ipConcurrencyLimiter {
+	return &ipConcurrencyLimiter{
+		inFlight: make(map[string]int),
+		limit:    limit,
+	}
+}
+
+// acquire reserves a slot for ip, returning false when the IP is at its limit
+func (l *ipConcurrencyLimiter) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[ip] >= l.limit {
+		return false
+	}
+	l.inFlight[ip]++
+	return true
+}
+
+// release frees a slot previously reserved for ip
+func (l *ipConcurrencyLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight[ip]--
+	if l.inFlight[ip] <= 0 {
+		delete(l.inFlight, ip)
+	}
+}
+
+// count returns the number of in-flight requests for ip
+func (l *ipConcurrencyLimiter) count(ip string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight[ip]
+}
+
+// Middleware rejects requests with 429 once a client IP has too many requests in flight
+func (l *ipConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := getClientIP(r)
+		if !l.acquire(ip) {
+			requestID := ""
+			if id := r.Context().Value("request_id"); id != nil {
+				requestID = id.(string)
+			}
+
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"remote_ip":  ip,
+				"limit":      l.limit,
+			}).Warn("Too many concurrent requests from client")
+
+			sendErrorResponse(w, APIError{
+				Type:       ErrorTypeRateLimited,
+				Message:    "Too many concurrent requests",
+				Details:    fmt.Sprintf("At most %d concurrent requests are allowed per client", l.limit),
+				StatusCode: http.StatusTooManyRequests,
+				RequestID:  requestID,
+			})
+			return
+		}
+		// Release in a defer so the slot is freed even if the handler panics
+		defer l.release(ip)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// defaultRequestTimeout bounds requests other than analyses, and analyzeTimeoutGrace
+// is added to ANALYZE_TIMEOUT for analyses; variables so tests can shorten them
+var (
+	defaultRequestTimeout = 5 * time.Second
+	analyzeTimeoutGrace   = 5 * time.Second
+)
+
+// analysisRoute reports whether path runs the analysis pipeline while the client
+// waits, and so gets the analysis budget instead of defaultRequestTimeout
+func analysisRoute(path string) bool {
+	if strings.HasPrefix(path, "/v1/analyze") {
+		return true
+	}
+	return strings.HasPrefix(path, "/v1/analyses/") && strings.HasSuffix(path, "/reanalyze")
+}
+
 // timeoutMiddleware adds request timeout to prevent hanging requests
 func timeoutMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set different timeouts based on endpoint
 		var timeout time.Duration
-		if strings.HasPrefix(r.URL.Path, "/v1/analyze") {
-			timeout = 25 * time.Second // Longer timeout for analysis
+		if analysisRoute(r.URL.Path) {
+			timeout = appConfig.AnalyzeTimeout + analyzeTimeoutGrace // Longer timeout for analysis
 		} else {
-			timeout = 5 * time.Second // Short timeout for health checks
+			timeout = defaultRequestTimeout // Short timeout for health checks
 		}
-		
+
 		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
-		
+
 		r = r.WithContext(ctx)
-		
+
+		// The handler may outlive the timeout, so it writes through a guarded writer
+		tw := &timeoutWriter{w: w, header: make(http.Header)}
+
 		// Channel to signal completion
 		done := make(chan struct{})
-		
+
 		go func() {
 			defer close(done)
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(tw, r)
 		}()
-		
+
 		select {
 		case <-done:
 			// Request completed normally
@@ -323,15 +754,15 @@ func timeoutMiddleware(next http.Handler) http.Handler {
 			if id := r.Context().Value("request_id"); id != nil {
 				requestID = id.(string)
 			}
-			
+
 			logger.WithFields(logrus.Fields{
 				"request_id": requestID,
 				"method":     r.Method,
 				"path":       r.URL.Path,
 				"timeout":    timeout,
 			}).Warn("Request timed out")
-			
-			sendErrorResponse(w, APIError{
+
+			tw.timeout(APIError{
 				Type:       ErrorTypeTimeout,
 				Message:    "Request timeout",
 				Details:    fmt.Sprintf("Request exceeded %v timeout", timeout),
@@ -342,6 +773,60 @@ func timeoutMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// timeoutWriter is the writer handed to a handler run by timeoutMiddleware. Once the
+// request times out, the handler's writes are dropped, so a handler still running
+// can never interleave with the timeout response or write after the middleware has
+// returned. Headers are kept apart until the handler writes for the same reason.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return tw.w.Write(p)
+}
+
+// timeout stops the handler's writes and sends apiErr, unless the handler has
+// already started its response
+func (tw *timeoutWriter) timeout(apiErr APIError) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	if !tw.wroteHeader {
+		sendErrorResponse(tw.w, apiErr)
+	}
+}
+
 // sendErrorResponse sends a structured error response
 func sendErrorResponse(w http.ResponseWriter, apiErr APIError) {
 	w.Header().Set("Content-Type", "application/json")
@@ -382,14 +867,56 @@ func validateURL(urlStr string) error {
 	return nil
 }
 
-// Global HTTP client with optimized connection pooling
-var httpClient *http.Client
+// Global HTTP client with optimized connection pooling. httpClientMu guards its lazy
+// initialization, which concurrent analyses such as the two sides of a compare can race to.
+var (
+	httpClient   *http.Client
+	httpClientMu sync.Mutex
+)
+
+// parseTLSVersion converts a version string such as "1.2" into a crypto/tls constant.
+// An empty string returns zero, which leaves the Go default in place.
+func parseTLSVersion(version string) (uint16, error) {
+	switch strings.TrimSpace(version) {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", version)
+	}
+}
+
+// outboundProxy returns the proxy selector for outbound fetches. An explicit FETCH_PROXY
+// takes precedence over the standard proxy environment variables.
+func outboundProxy(fetchProxy string) func(*http.Request) (*url.URL, error) {
+	if fetchProxy == "" {
+		return http.ProxyFromEnvironment
+	}
+
+	proxyURL, err := parseProxyURL(fetchProxy)
+	if err != nil {
+		// Fail closed rather than silently bypassing the configured egress
+		return func(*http.Request) (*url.URL, error) {
+			return nil, fmt.Errorf("invalid FETCH_PROXY: %v", err)
+		}
+	}
+	return http.ProxyURL(proxyURL)
+}
 
 // initHTTPClient initializes the global HTTP client with optimized settings
 func initHTTPClient() {
+	// No overall Timeout: each fetch is bounded by its analysis context, whose
+	// budget depends on the request, and by responseHeaderTimeout per attempt
 	httpClient = &http.Client{
-		Timeout: 15 * time.Second,
 		Transport: &http.Transport{
+			Proxy: outboundProxy(appConfig.FetchProxy),
 			DialContext: (&net.Dialer{
 				Timeout:   5 * time.Second,
 				KeepAlive: 30 * time.Second,
@@ -400,9 +927,8 @@ func initHTTPClient() {
 			MaxConnsPerHost:       50,
 			IdleConnTimeout:       90 * time.Second,
 			TLSHandshakeTimeout:   10 * time.Second,
+			TLSClientConfig:       &tls.Config{MinVersion: outboundMinTLSVersion},
 			ExpectContinueTimeout: 1 * time.Second,
-			// Response header timeout to prevent hanging
-			ResponseHeaderTimeout: 10 * time.Second,
 			// Disable compression to reduce CPU usage
 			DisableCompression: false,
 			// Force HTTP/2 for better performance
@@ -410,16 +936,113 @@ func initHTTPClient() {
 		},
 		// Limit redirects to prevent infinite loops
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("stopped after 10 redirects")
+			if len(via) >= maxRedirects {
+				urls := make([]string, 0, len(via)+1)
+				for _, r := range via {
+					if r != nil {
+						urls = append(urls, r.URL.String())
+					}
+				}
+				return &tooManyRedirectsError{Count: len(via), URLs: append(urls, req.URL.String())}
+			}
+			// Record the hop if the caller asked for the redirect chain
+			if rec, ok := req.Context().Value(redirectRecorderKey{}).(*redirectRecorder); ok {
+				rec.hops = append(rec.hops, req.URL.String())
 			}
 			return nil
 		},
 	}
 }
 
+// maxRedirects is the number of redirects followed before a fetch is abandoned
+const maxRedirects = 10
+
+// tooManyRedirectsError is returned when a fetch exceeds maxRedirects, usually
+// because the target redirects in a loop
+type tooManyRedirectsError struct {
+	Count int
+	URLs  []string // every URL requested, in order
+}
+
+func (e *tooManyRedirectsError) Error() string {
+	return fmt.Sprintf("stopped after %d redirects", e.Count)
+}
+
+// LoopURLs returns the URLs that were visited more than once, in first-seen order.
+// A long chain without repeats returns nil.
+func (e *tooManyRedirectsError) LoopURLs() []string {
+	seen := make(map[string]int, len(e.URLs))
+	for _, u := range e.URLs {
+		seen[u]++
+	}
+
+	var loop []string
+	for _, u := range e.URLs {
+		if seen[u] > 1 {
+			loop = append(loop, u)
+			seen[u] = 0
+		}
+	}
+	return loop
+}
+
+// redirectRecorder collects the URLs visited while following redirects for a single request
+type redirectRecorder struct {
+	hops []string
+}
+
+// redirectRecorderKey is the context key under which a *redirectRecorder is stored
+type redirectRecorderKey struct{}
+
+// withRedirectRecorder returns a context that records redirect hops into rec
+func withRedirectRecorder(ctx context.Context, rec *redirectRecorder) context.Context {
+	return context.WithValue(ctx, redirectRecorderKey{}, rec)
+}
+
+// buildRedirectHops returns the full chain of visited URLs, starting with the requested one
+func buildRedirectHops(requestedURL string, redirects []string) []RedirectHop {
+	hops := make([]RedirectHop, 0, len(redirects)+1)
+	for _, hop := range append([]string{requestedURL}, redirects...) {
+		scheme := ""
+		if parsed, err := url.Parse(hop); err == nil {
+			scheme = parsed.Scheme
+		}
+		hops = append(hops, RedirectHop{URL: hop, Scheme: scheme})
+	}
+	return hops
+}
+
+// detectSchemeDowngrades flags any hop that redirects from HTTPS to plain HTTP
+func detectSchemeDowngrades(hops []RedirectHop) []SecurityFinding {
+	var findings []SecurityFinding
+	for i := 1; i < len(hops); i++ {
+		if hops[i-1].Scheme == "https" && hops[i].Scheme == "http" {
+			findings = append(findings, SecurityFinding{
+				Type:     "https_downgrade",
+				Severity: "high",
+				Subject:  redirectSubject(hops[i].URL),
+				Message:  fmt.Sprintf("Redirect from %s downgrades to insecure %s", hops[i-1].URL, hops[i].URL),
+			})
+		}
+	}
+	return findings
+}
+
+// redirectSubject identifies a redirect target by its path, which stays the same
+// when the site is served from another host
+func redirectSubject(target string) string {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Path == "" {
+		return "/"
+	}
+	return parsed.Path
+}
+
 // createHTTPClient returns the optimized global HTTP client
 func createHTTPClient() *http.Client {
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+
 	// Initialize if not already done (for tests)
 	if httpClient == nil {
 		initHTTPClient()
@@ -427,6 +1050,20 @@ func createHTTPClient() *http.Client {
 	return httpClient
 }
 
+// decodeBody returns body with a gzip Content-Encoding removed. Other encodings are
+// passed through as-is, as the transport would have done; fetchPage only asks for gzip.
+func decodeBody(header http.Header, body io.Reader) (io.Reader, error) {
+	if !strings.EqualFold(strings.TrimSpace(header.Get("Content-Encoding")), "gzip") {
+		return body, nil
+	}
+	reader, err := gzip.NewReader(body)
+	if err == io.EOF {
+		// An empty body has nothing to decode
+		return body, nil
+	}
+	return reader, err
+}
+
 // readResponseBody reads response body with memory optimization
 func readResponseBody(reader io.Reader, maxSize int64) ([]byte, error) {
 	// Use a buffer with initial capacity to reduce allocations
@@ -548,33 +1185,256 @@ func analyzeHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+
+	// ?analyzers=a,b selects analyzers when the body does not
+	if value := r.URL.Query().Get("analyzers"); value != "" && req.Analyzers == nil {
+		for _, name := range strings.Split(value, ",") {
+			req.Analyzers = append(req.Analyzers, strings.TrimSpace(name))
+		}
+	}
+	
+	page, opts, apiErr := prepareAnalysis(requestID, req, appConfig.AnalyzeTimeout)
+	if apiErr != nil {
+		sendErrorResponse(w, *apiErr)
+		return
+	}
+
+	// With a callback the analysis runs as a job and the result is POSTed to the callback URL
+	if req.CallbackURL != "" {
+		enqueueJob(w, requestID, req, opts, page)
+		return
+	}
+	
+	logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"url":        req.URL,
+		"profile":    opts.Profile,
+	}).Info("Starting URL analysis")
+	
+	// Create context with timeout for the entire request processing
+	ctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+	defer cancel()
+
+	result, apiErr := runAnalysis(ctx, requestID, req, opts, page)
+	if apiErr != nil {
+		sendErrorResponse(w, *apiErr)
+		return
+	}
 	
-	// Validate URL field
-	if err := validateURL(req.URL); err != nil {
+	// Return successful analysis results
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
 		logger.WithFields(logrus.Fields{
 			"request_id": requestID,
-			"url":        req.URL,
 			"error":      err,
-		}).Warn("URL validation failed")
+		}).Error("Failed to encode analysis response")
 		
 		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeInternal,
+			Message:    "Failed to generate response",
+			Details:    "Error occurred while encoding the response",
+			StatusCode: http.StatusInternalServerError,
+			RequestID:  requestID,
+		})
+	}
+}
+
+// prepareAnalysis validates an analysis request, returning the inline page when one was
+// supplied and the scan options resolved within maxTimeout
+func prepareAnalysis(requestID string, req AnalyzeRequest, maxTimeout time.Duration) (*fetchedPage, ScanOptions, *APIError) {
+	// Exactly one of url or html selects the page; inline HTML and data: URLs skip the fetch
+	page, err := inlineSource(req)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Warn("Inline HTML validation failed")
+
+		return nil, ScanOptions{}, &APIError{
 			Type:       ErrorTypeValidation,
-			Message:    "Invalid URL",
+			Message:    "Invalid inline HTML",
 			Details:    err.Error(),
 			StatusCode: http.StatusBadRequest,
 			RequestID:  requestID,
+		}
+	}
+
+	// Validate URL field unless the page was supplied inline
+	if page == nil {
+		if err := validateURL(req.URL); err != nil {
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"url":        req.URL,
+				"error":      err,
+			}).Warn("URL validation failed")
+
+			return nil, ScanOptions{}, &APIError{
+				Type:       ErrorTypeValidation,
+				Message:    "Invalid URL",
+				Details:    err.Error(),
+				StatusCode: http.StatusBadRequest,
+				RequestID:  requestID,
+			}
+		}
+	}
+
+	// Callbacks get the same URL checks as the analyzed page and must be signable
+	if req.CallbackURL != "" {
+		if appConfig.CallbackSecret == "" {
+			return nil, ScanOptions{}, &APIError{
+				Type:       ErrorTypeValidation,
+				Message:    "Callbacks are disabled",
+				Details:    "Set CALLBACK_SECRET to enable callback_url",
+				StatusCode: http.StatusBadRequest,
+				RequestID:  requestID,
+			}
+		}
+		if err := validateURL(req.CallbackURL); err != nil {
+			logger.WithFields(logrus.Fields{
+				"request_id":   requestID,
+				"callback_url": req.CallbackURL,
+				"error":        err,
+			}).Warn("Callback URL validation failed")
+
+			return nil, ScanOptions{}, &APIError{
+				Type:       ErrorTypeValidation,
+				Message:    "Invalid callback URL",
+				Details:    err.Error(),
+				StatusCode: http.StatusBadRequest,
+				RequestID:  requestID,
+			}
+		}
+	}
+
+	// Apply the scan profile and explicit request options
+	opts, err := resolveScanOptionsWithin(req, maxTimeout)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"profile":    req.Profile,
+			"error":      err,
+		}).Warn("Scan options validation failed")
+
+		return nil, ScanOptions{}, &APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid scan options",
+			Details:    err.Error(),
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		}
+	}
+
+	return page, opts, nil
+}
+
+// runAnalysis fetches the page unless it was supplied inline, keeps it for re-analysis
+// when a page store is configured, and analyzes it
+func runAnalysis(ctx context.Context, requestID string, req AnalyzeRequest, opts ScanOptions, page *fetchedPage) (*AnalyzeResponse, *APIError) {
+	fetched := page == nil
+	if fetched {
+		var apiErr *APIError
+		page, apiErr = fetchPage(ctx, requestID, req, opts)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	// Keep the raw page for later re-analysis when a page store is configured
+	var analysisID string
+	if pageStore != nil {
+		var err error
+		analysisID, err = savePage(ctx, pageStore, appConfig.PageStoreMaxBytes, req.URL, page)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"url":        req.URL,
+				"error":      err,
+			}).Warn("Failed to store page for re-analysis")
+		} else if analysisID == "" {
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"url":        req.URL,
+				"size":       len(page.Body),
+			}).Debug("Page exceeds PAGE_STORE_MAX_BYTES, not stored")
+		}
+	}
+
+	result, apiErr := analyzePage(requestID, req, opts, page)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	// Probing sends extra requests to the live site, so it only follows a fetch. Results
+	// are cached per site, so analyzing the same site again within PROBE_CACHE_TTL does
+	// not probe it again.
+	if opts.ProbeSensitivePaths && fetched {
+		start := time.Now()
+		value, err := probeResults.cached(probeCacheKey("sensitive_paths", siteOrigin(page.FinalURL)), func() (interface{}, error) {
+			return probeSensitivePaths(ctx, page.FinalURL, appConfig.SensitivePaths, appConfig.Probe)
 		})
-		return
+		exposed, _ := value.([]string)
+		result.ExposedPaths = exposed
+		result.SecurityFindings = append(result.SecurityFindings, exposedPathFindings(exposed)...)
+		if err != nil {
+			result.Warnings = append(result.Warnings, sensitivePathsTimeoutWarning)
+		}
+		result.Timings["sensitive_paths"] = durationMs(time.Since(start))
 	}
-	
-	logger.WithFields(logrus.Fields{
-		"request_id": requestID,
-		"url":        req.URL,
-	}).Info("Starting URL analysis")
-	
-	// Create context with timeout for the entire request processing
-	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
-	defer cancel()
+	if opts.ProbeCORS && fetched {
+		start := time.Now()
+		value, err := probeResults.cached(probeCacheKey("cors", page.FinalURL), func() (interface{}, error) {
+			return probeCORSOrigin(ctx, page.FinalURL, appConfig.Probe)
+		})
+		findings, _ := value.([]SecurityFinding)
+		result.SecurityFindings = append(result.SecurityFindings, findings...)
+		if err != nil {
+			result.Warnings = append(result.Warnings, corsProbeTimeoutWarning)
+		}
+		result.Timings["cors_probe"] = durationMs(time.Since(start))
+	}
+	if opts.ProbeHTTPSRedirect && fetched {
+		start := time.Now()
+		value, err := probeResults.cached(probeCacheKey("https_redirect", siteOrigin(page.FinalURL)), func() (interface{}, error) {
+			return probeHTTPSRedirect(ctx, page.FinalURL, appConfig.Probe)
+		})
+		check, _ := value.(*HTTPSRedirectCheck)
+		result.HTTPSRedirect = check
+		result.SecurityFindings = append(result.SecurityFindings, httpsRedirectFindings(check)...)
+		if err != nil {
+			result.Warnings = append(result.Warnings, httpsRedirectTimeoutWarning)
+		}
+		result.Timings["https_redirect_probe"] = durationMs(time.Since(start))
+	}
+	result.AnalysisID = analysisID
+	result.DeadlineMs = opts.Timeout.Milliseconds()
+	return result, nil
+}
+
+// fetchedPage is the input to the analysis pipeline, either fetched from the
+// requested URL or supplied inline
+type fetchedPage struct {
+	Header     http.Header
+	Body       []byte
+	FinalURL   string
+	StatusCode int
+	Redirects  []string      // URLs visited while following redirects
+	Hops       []RedirectHop // full chain including the requested URL; nil for inline pages
+	FetchTime  time.Duration // zero for inline and stored pages
+	Phases     fetchPhases   // network phase timings; zero for inline and stored pages
+}
+
+// fetchPage fetches the requested URL, following redirects and retrying transient failures
+func fetchPage(ctx context.Context, requestID string, req AnalyzeRequest, opts ScanOptions) (*fetchedPage, *APIError) {
+	start := time.Now()
+
+	// Track redirect hops so the response can report where the request landed
+	redirects := &redirectRecorder{}
+	ctx = withRedirectRecorder(ctx, redirects)
+
+	// Measure DNS, connect, TLS and time-to-first-byte for the load time breakdown
+	tracer := &fetchTracer{}
+	ctx = withFetchTracer(ctx, tracer)
 
 	// Create HTTP request with context for proper timeout handling
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", req.URL, nil)
@@ -585,26 +1445,43 @@ func analyzeHandler(w http.ResponseWriter, r *http.Request) {
 			"error":      err,
 		}).Error("Failed to create HTTP request")
 		
-		sendErrorResponse(w, APIError{
+		return nil, &APIError{
 			Type:       ErrorTypeInternal,
 			Message:    "Failed to create request",
 			Details:    "Unable to create HTTP request",
 			StatusCode: http.StatusInternalServerError,
 			RequestID:  requestID,
-		})
-		return
+		}
 	}
 
 	// Set user agent to identify our service
-	httpReq.Header.Set("User-Agent", "WebAIlyzer-Lite-API/1.0")
+	httpReq.Header.Set("User-Agent", opts.UserAgent)
+	// Asking for gzip ourselves stops the transport decompressing transparently, which
+	// would drop the Content-Encoding and Content-Length the site sent
+	httpReq.Header.Set("Accept-Encoding", "gzip")
 	
-	// Fetch URL with optimized client
+	// Fetch URL with optimized client, retrying transient failures
 	client := createHTTPClient()
-	resp, err := client.Do(httpReq)
+	resp, err := fetchWithRetry(ctx, client, httpReq, appConfig.FetchMaxAttempts, responseHeaderTimeout(opts.Timeout))
 	if err != nil {
 		// Determine error type based on error details
 		var apiErr APIError
-		if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline exceeded") {
+		var redirectErr *tooManyRedirectsError
+		if errors.As(err, &redirectErr) {
+			details := fmt.Sprintf("Stopped after %d redirects", redirectErr.Count)
+			if loop := redirectErr.LoopURLs(); len(loop) > 0 {
+				details += "; redirect loop between: " + strings.Join(loop, " -> ")
+			} else {
+				details += "; redirect chain: " + strings.Join(redirectErr.URLs, " -> ")
+			}
+			apiErr = APIError{
+				Type:       ErrorTypeTooManyRedirects,
+				Message:    "Too many redirects",
+				Details:    details,
+				StatusCode: http.StatusBadGateway,
+				RequestID:  requestID,
+			}
+		} else if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline exceeded") {
 			apiErr = APIError{
 				Type:       ErrorTypeTimeout,
 				Message:    "Request timeout",
@@ -637,13 +1514,29 @@ func analyzeHandler(w http.ResponseWriter, r *http.Request) {
 			"error_type": apiErr.Type,
 		}).Error("Failed to fetch URL")
 		
-		sendErrorResponse(w, apiErr)
-		return
+		return nil, &apiErr
 	}
 	defer resp.Body.Close()
 	
-	// Check HTTP status code
-	if resp.StatusCode >= 400 {
+	// Read response body with size limit and proper cleanup
+	maxBodySize := appConfig.MaxBodyBytes
+	
+	// Use a buffer pool for memory efficiency. The limit applies to the decoded body.
+	readStart := time.Now()
+	var body []byte
+	decoded, readErr := decodeBody(resp.Header, resp.Body)
+	if readErr == nil {
+		body, readErr = readResponseBody(io.LimitReader(decoded, maxBodySize), maxBodySize)
+	}
+	tracer.setTransfer(time.Since(readStart))
+
+	// Check HTTP status code. Bot challenges are often served as 403 or 405; those are
+	// analyzed and flagged rather than reported as the site's own error.
+	challenge := false
+	if resp.StatusCode >= 400 && readErr == nil {
+		challenge, _ = detectChallenge(resp.StatusCode, resp.Header, body, challengeSignatures)
+	}
+	if resp.StatusCode >= 400 && !challenge {
 		logger.WithFields(logrus.Fields{
 			"request_id":  requestID,
 			"url":         req.URL,
@@ -677,70 +1570,160 @@ func analyzeHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		
-		sendErrorResponse(w, apiErr)
-		return
+		return nil, &apiErr
 	}
 	
-	// Read response body with size limit and proper cleanup
-	const maxBodySize = 5 * 1024 * 1024 // 5MB limit for memory optimization
-	limitedReader := io.LimitReader(resp.Body, maxBodySize)
-	
-	// Use a buffer pool for memory efficiency
-	body, err := readResponseBody(limitedReader, maxBodySize)
-	if err != nil {
+	if readErr != nil {
 		logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"url":        req.URL,
-			"error":      err,
+			"error":      readErr,
 		}).Error("Failed to read response body")
 		
-		sendErrorResponse(w, APIError{
+		return nil, &APIError{
 			Type:       ErrorTypeNetwork,
 			Message:    "Failed to read response",
 			Details:    "Error occurred while reading the response body",
 			StatusCode: http.StatusBadGateway,
 			RequestID:  requestID,
-		})
-		return
+		}
 	}
-	
-	// Initialize wappalyzer engine
-	wc, err := wappalyzer.New()
+
+	// Record the scheme of every hop so downgrades can be flagged
+	return &fetchedPage{
+		Header:     resp.Header,
+		Body:       body,
+		FinalURL:   resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Redirects:  redirects.hops,
+		Hops:       buildRedirectHops(req.URL, redirects.hops),
+		FetchTime:  time.Since(start),
+		Phases:     tracer.Phases(),
+	}, nil
+}
+
+// durationMs converts d to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// sanitizeUTF8 replaces invalid UTF-8 sequences with the Unicode replacement character,
+// returning body unchanged when it is already valid
+func sanitizeUTF8(body []byte) []byte {
+	if utf8.Valid(body) {
+		return body
+	}
+	return bytes.ToValidUTF8(body, []byte(string(utf8.RuneError)))
+}
+
+// analyzePage fingerprints the page and builds the analysis response
+func analyzePage(requestID string, req AnalyzeRequest, opts ScanOptions, page *fetchedPage) (*AnalyzeResponse, *APIError) {
+	// Get the shared wappalyzer engine
+	wc, err := getWappalyzer()
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"error":      err,
 		}).Error("Wappalyzer initialization failed")
 		
-		sendErrorResponse(w, APIError{
+		return nil, &APIError{
 			Type:       ErrorTypeInternal,
 			Message:    "Technology detection engine failed",
 			Details:    "Unable to initialize the technology detection engine",
 			StatusCode: http.StatusInternalServerError,
 			RequestID:  requestID,
-		})
-		return
+		}
 	}
 	
+	// Guard the regex-based analyzers from binary or truncated multibyte input
+	page.Body = sanitizeUTF8(page.Body)
+
+	// Time each analyzer so the response can show where the time went
+	timings := make(map[string]float64)
+	if page.FetchTime > 0 {
+		timings["fetch"] = durationMs(page.FetchTime)
+	}
+	start := time.Now()
+	lap := func(name string) {
+		now := time.Now()
+		timings[name] = durationMs(now.Sub(start))
+		start = now
+	}
+
 	// Perform technology fingerprinting with detailed information
-	detected := wc.FingerprintWithInfo(resp.Header, body)
+	detected := wc.FingerprintWithInfo(page.Header, page.Body)
+	lap("technologies")
+
+	// Create response with detected technologies
+	result := AnalyzeResponse{
+		URL:             req.URL,
+		Profile:         opts.Profile,
+		FinalURL:        page.FinalURL,
+		Redirected:      len(page.Redirects) > 0,
+		RedirectChain:   page.Redirects,
+		RedirectHops:    page.Hops,
+		Detected:        make(map[string]interface{}),
+		ContentType:     page.Header.Get("Content-Type"),
+		StatusCode:      page.StatusCode,
+		ResponseHeaders: selectResponseHeaders(page.Header),
+		Timings:         timings,
+	}
+
+	// Run the selected analyzers over the page, timing each one
+	selected, err := analyzers.Select(opts.Analyzers)
+	if err != nil {
+		return nil, &APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid scan options",
+			Details:    err.Error(),
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		}
+	}
+	for _, a := range selected {
+		a.Analyze(page, &result)
+		lap(a.Name())
+	}
 	
 	// Clear body from memory immediately after processing
-	body = nil
+	page.Body = nil
 	runtime.GC() // Suggest garbage collection to free memory
 	
 	logger.WithFields(logrus.Fields{
 		"request_id":         requestID,
 		"url":                req.URL,
 		"technologies_found": len(detected),
-		"content_type":       resp.Header.Get("Content-Type"),
+		"content_type":       page.Header.Get("Content-Type"),
 	}).Info("Analysis completed successfully")
 	
-	// Create response with detected technologies
-	result := AnalyzeResponse{
-		URL:         req.URL,
-		Detected:    make(map[string]interface{}),
-		ContentType: resp.Header.Get("Content-Type"),
+	downgrades := 0
+	for _, f := range result.SecurityFindings {
+		if f.Type == "https_downgrade" {
+			downgrades++
+		}
+	}
+	if downgrades > 0 {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"url":        req.URL,
+			"findings":   downgrades,
+		}).Warn("Redirect chain downgrades from HTTPS to HTTP")
+	}
+
+	if result.ChallengeDetected {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"url":        req.URL,
+			"provider":   result.ChallengeProvider,
+		}).Warn("Analyzed page looks like a bot challenge")
+	}
+
+	if page.FetchTime > 0 {
+		result.LoadTime = page.Phases.Metrics()
+	}
+
+	if opts.IncludeHeaders {
+		result.Headers = sanitizeHeaders(page.Header)
 	}
 	
 	// Convert detected technologies to interface{} map
@@ -748,21 +1731,5 @@ func analyzeHandler(w http.ResponseWriter, r *http.Request) {
 		result.Detected[tech] = info
 	}
 	
-	// Return successful analysis results
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		logger.WithFields(logrus.Fields{
-			"request_id": requestID,
-			"error":      err,
-		}).Error("Failed to encode analysis response")
-		
-		sendErrorResponse(w, APIError{
-			Type:       ErrorTypeInternal,
-			Message:    "Failed to generate response",
-			Details:    "Error occurred while encoding the response",
-			StatusCode: http.StatusInternalServerError,
-			RequestID:  requestID,
-		})
-	}
+	return &result, nil
 }
\ No newline at end of file
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
 func TestHealthHandler(t *testing.T) {
@@ -401,10 +406,9 @@ func TestCreateHTTPClient(t *testing.T) {
 		t.Error("createHTTPClient returned nil")
 	}
 	
-	// Check timeout is set correctly
-	expectedTimeout := 15 * time.Second
-	if client.Timeout != expectedTimeout {
-		t.Errorf("client timeout should be %v, got %v", expectedTimeout, client.Timeout)
+	// Fetches are bounded by the analysis budget rather than a client timeout
+	if client.Timeout != 0 {
+		t.Errorf("client timeout should be unset, got %v", client.Timeout)
 	}
 	
 	// Check transport is configured
@@ -432,6 +436,118 @@ func TestCreateHTTPClient(t *testing.T) {
 	}
 }
 
+func TestHTTPClientMinTLSVersion(t *testing.T) {
+	defer func() {
+		outboundMinTLSVersion = 0
+		initHTTPClient()
+	}()
+
+	version, err := parseTLSVersion("1.2")
+	if err != nil {
+		t.Fatalf("unexpected error parsing TLS version: %v", err)
+	}
+	outboundMinTLSVersion = version
+	initHTTPClient()
+
+	transport, ok := createHTTPClient().Transport.(*http.Transport)
+	if !ok {
+		t.Fatal("client transport should be *http.Transport")
+	}
+
+	if transport.TLSClientConfig == nil {
+		t.Fatal("TLSClientConfig should be set")
+	}
+
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion should be TLS 1.2, got %#x", transport.TLSClientConfig.MinVersion)
+	}
+}
+
+func TestOutboundProxyRoutesRequests(t *testing.T) {
+	// Stub proxy that answers on behalf of any origin
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Served by proxy</body></html>`))
+	}))
+	defer proxy.Close()
+
+	originalConfig := appConfig
+	defer func() {
+		appConfig = originalConfig
+		initHTTPClient()
+	}()
+	appConfig.FetchProxy = proxy.URL
+	initHTTPClient()
+
+	requestBody := `{"url":"http://origin.invalid/page"}`
+	req, err := http.NewRequest("POST", "/v1/analyze", strings.NewReader(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 via proxy, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if proxiedHost != "origin.invalid" {
+		t.Errorf("expected request for origin.invalid to reach the proxy, got %q", proxiedHost)
+	}
+}
+
+func TestOutboundProxySelection(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://origin.example/", nil)
+
+	explicit, err := outboundProxy("socks5://127.0.0.1:1080")(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if explicit.String() != "socks5://127.0.0.1:1080" {
+		t.Errorf("FETCH_PROXY should be used for every request, got %v", explicit)
+	}
+
+	// An invalid proxy must fail closed instead of connecting directly
+	if _, err := outboundProxy("ftp://proxy.example")(req); err == nil {
+		t.Error("invalid proxy should return an error")
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  uint16
+		expectErr bool
+	}{
+		{"", 0, false},
+		{"1.0", tls.VersionTLS10, false},
+		{"1.1", tls.VersionTLS11, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"2.0", 0, true},
+		{"tls1.2", 0, true},
+	}
+
+	for _, tt := range tests {
+		version, err := parseTLSVersion(tt.input)
+		if tt.expectErr {
+			if err == nil {
+				t.Errorf("parseTLSVersion(%q) should return an error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTLSVersion(%q) returned unexpected error: %v", tt.input, err)
+		}
+		if version != tt.expected {
+			t.Errorf("parseTLSVersion(%q) = %#x, want %#x", tt.input, version, tt.expected)
+		}
+	}
+}
+
 func TestAnalyzeHandlerWappalyzerIntegration(t *testing.T) {
 	// Test with a real URL that should work
 	requestBody := `{"url":"https://httpbin.org/html"}`
@@ -471,6 +587,10 @@ func TestAnalyzeHandlerWappalyzerIntegration(t *testing.T) {
 		t.Error("content_type should not be empty")
 	}
 
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("status_code should be 200, got %d", response.StatusCode)
+	}
+
 	// Check content type header
 	expected := "application/json"
 	if contentType := rr.Header().Get("Content-Type"); contentType != expected {
@@ -587,6 +707,10 @@ func TestCompleteAnalysisFlow(t *testing.T) {
 				t.Error("content_type field should not be empty")
 			}
 
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("status_code field should be 200, got %d", response.StatusCode)
+			}
+
 			// Log detected technologies for debugging
 			t.Logf("URL: %s", response.URL)
 			t.Logf("Content-Type: %s", response.ContentType)
@@ -622,7 +746,7 @@ func TestAnalysisResponseFormat(t *testing.T) {
 	}
 
 	// Verify required top-level fields exist
-	requiredFields := []string{"url", "detected", "content_type"}
+	requiredFields := []string{"url", "detected", "content_type", "status_code"}
 	for _, field := range requiredFields {
 		if _, exists := responseMap[field]; !exists {
 			t.Errorf("response missing required field: %s", field)
@@ -655,6 +779,206 @@ func TestAnalysisResponseFormat(t *testing.T) {
 		t.Error("content_type field should be a string")
 	}
 }
+func TestAnalyzeResponseStatusAndHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Header().Set("X-Powered-By", "PHP/8.2.0")
+		w.Header().Set("X-Internal-Token", "secret")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`<html><body>Hello</body></html>`))
+	}))
+	defer server.Close()
+
+	requestBody := fmt.Sprintf(`{"url":"%s"}`, server.URL)
+	req, err := http.NewRequest("POST", "/v1/analyze", strings.NewReader(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected status_code 200, got %d", response.StatusCode)
+	}
+
+	expectedHeaders := map[string]string{
+		"Server":         "nginx/1.25.3",
+		"X-Powered-By":   "PHP/8.2.0",
+		"Content-Length": "31",
+	}
+	for name, value := range expectedHeaders {
+		if response.ResponseHeaders[name] != value {
+			t.Errorf("expected header %s=%q, got %q", name, value, response.ResponseHeaders[name])
+		}
+	}
+
+	// Headers outside the allowlist must not leak into the response
+	if _, exists := response.ResponseHeaders["X-Internal-Token"]; exists {
+		t.Error("non-allowlisted header X-Internal-Token should not be included")
+	}
+}
+
+func TestAnalyzeResponseHeadersFromGzipUpstream(t *testing.T) {
+	html := `<html><head><title>Compressed</title><script src="/wp-includes/js/jquery/jquery.js"></script></head><body>Hello</body></html>`
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(html))
+	gz.Close()
+
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(compressed.Len()))
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	rr := postAnalyze(t, map[string]string{"url": server.URL})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if acceptEncoding != "gzip" {
+		t.Errorf("expected the fetch to ask for gzip, got Accept-Encoding %q", acceptEncoding)
+	}
+	if got := response.ResponseHeaders["Content-Encoding"]; got != "gzip" {
+		t.Errorf("expected Content-Encoding gzip to be reported, got %q", got)
+	}
+	if got, want := response.ResponseHeaders["Content-Length"], strconv.Itoa(compressed.Len()); got != want {
+		t.Errorf("expected the compressed Content-Length %s, got %q", want, got)
+	}
+	// The body must still be decompressed before analysis
+	if _, ok := response.Detected["jQuery"]; !ok {
+		t.Errorf("expected jQuery to be detected from the decompressed body, got %v", response.Detected)
+	}
+}
+
+func TestAnalyzeIncludeHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Add("Set-Cookie", "session=abc123; Path=/; HttpOnly")
+		w.Header().Add("Set-Cookie", "theme=dark")
+		w.Write([]byte(`<html><body>Hello</body></html>`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		includeHeaders bool
+	}{
+		{"headers omitted by default", false},
+		{"headers included on request", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestBody := fmt.Sprintf(`{"url":"%s","include_headers":%t}`, server.URL, tt.includeHeaders)
+			req, err := http.NewRequest("POST", "/v1/analyze", strings.NewReader(requestBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			analyzeHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			var response AnalyzeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if !tt.includeHeaders {
+				if response.Headers != nil {
+					t.Errorf("headers should be omitted, got %v", response.Headers)
+				}
+				return
+			}
+
+			if got := response.Headers["Cache-Control"]; len(got) != 1 || got[0] != "no-cache" {
+				t.Errorf("expected Cache-Control header, got %v", got)
+			}
+
+			cookies := response.Headers["Set-Cookie"]
+			if len(cookies) != 2 {
+				t.Fatalf("expected 2 redacted cookies, got %v", cookies)
+			}
+			for _, cookie := range cookies {
+				if strings.Contains(cookie, "abc123") || strings.Contains(cookie, "dark") {
+					t.Errorf("cookie value should be redacted, got %s", cookie)
+				}
+			}
+			if cookies[0] != "session=[REDACTED]" {
+				t.Errorf("expected cookie name to be kept, got %s", cookies[0])
+			}
+		})
+	}
+}
+
+func TestCategoriesHandler(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/categories", categoriesHandler).Methods("GET")
+
+	req, err := http.NewRequest("GET", "/v1/categories", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /v1/categories returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response CategoriesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Categories) == 0 {
+		t.Fatal("expected categories to be listed")
+	}
+
+	found := false
+	for i, category := range response.Categories {
+		if i > 0 && response.Categories[i-1].ID >= category.ID {
+			t.Errorf("categories should be sorted by id, got %d after %d", category.ID, response.Categories[i-1].ID)
+		}
+		if category.Name == "CMS" {
+			found = true
+			if category.ID != 1 {
+				t.Errorf("expected CMS to have id 1, got %d", category.ID)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected CMS category to be listed")
+	}
+}
+
 // Test error handling middleware
 func TestErrorHandlingMiddleware(t *testing.T) {
 	// Create a test handler that we can control
@@ -783,6 +1107,89 @@ func TestLoggingMiddleware(t *testing.T) {
 	// but should log the request (we can't easily test the logging output in unit tests)
 }
 
+func TestLoggingMiddlewareSampling(t *testing.T) {
+	var buf bytes.Buffer
+	originalOut, originalFormatter, originalSampler := logger.Out, logger.Formatter, requestLogSampler
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	requestLogSampler = newLogSampler(10)
+	defer func() {
+		logger.SetOutput(originalOut)
+		logger.SetFormatter(originalFormatter)
+		requestLogSampler = originalSampler
+	}()
+
+	handler := errorHandlingMiddleware(loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	const requests = 1000
+	for i := 0; i < requests; i++ {
+		path := "/ok"
+		if i%4 == 0 {
+			path = "/fail"
+		}
+		req := httptest.NewRequest("GET", path, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var successLogged, errorsLogged int
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if entry["msg"] != "Request completed" {
+			continue
+		}
+		if entry["status_code"] == float64(http.StatusInternalServerError) {
+			if entry["level"] != "error" {
+				t.Errorf("expected failed request logged at error level, got %v", entry["level"])
+			}
+			errorsLogged++
+		} else {
+			successLogged++
+		}
+	}
+
+	if errorsLogged != requests/4 {
+		t.Errorf("expected every one of %d failed requests logged, got %d", requests/4, errorsLogged)
+	}
+	// The sampler counts all requests, so roughly 1 in 10 of the successful ones is kept
+	if successLogged < 50 || successLogged > 100 {
+		t.Errorf("expected about %d successful requests logged, got %d", requests*3/4/10, successLogged)
+	}
+}
+
+func TestLogSampler(t *testing.T) {
+	tests := []struct {
+		rate int
+		want int
+	}{
+		{0, 100},
+		{1, 100},
+		{10, 10},
+		{3, 34},
+	}
+
+	for _, tt := range tests {
+		s := newLogSampler(tt.rate)
+		sampled := 0
+		for i := 0; i < 100; i++ {
+			if s.Sample() {
+				sampled++
+			}
+		}
+		if sampled != tt.want {
+			t.Errorf("rate %d: expected %d sampled of 100, got %d", tt.rate, tt.want, sampled)
+		}
+	}
+}
+
 func TestResponseWriter(t *testing.T) {
 	// Test the custom responseWriter wrapper
 	rr := httptest.NewRecorder()
@@ -1048,6 +1455,49 @@ func TestGenerateRequestID(t *testing.T) {
 	}
 }
 
+func TestErrorHandlingMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		inbound  string
+		expected string // empty means a generated ID
+	}{
+		{"provided valid", "7f1c2a9e-3b4d-4e5f-8a6b-1c2d3e4f5a6b", "7f1c2a9e-3b4d-4e5f-8a6b-1c2d3e4f5a6b"},
+		{"provided invalid", "bad id\r\nX-Injected: 1", ""},
+		{"provided too long", strings.Repeat("a", 129), ""},
+		{"absent", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextID string
+			handler := errorHandlingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID = r.Context().Value("request_id").(string)
+			}))
+
+			req := httptest.NewRequest("GET", "/health", nil)
+			if tt.inbound != "" {
+				req.Header["X-Request-Id"] = []string{tt.inbound}
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			echoed := rr.Header().Get("X-Request-ID")
+			if echoed != contextID {
+				t.Errorf("expected the response header to echo the context ID %q, got %q", contextID, echoed)
+			}
+			if tt.expected != "" {
+				if contextID != tt.expected {
+					t.Errorf("expected the inbound ID %q to be reused, got %q", tt.expected, contextID)
+				}
+				return
+			}
+			if contextID == "" || contextID == tt.inbound {
+				t.Errorf("expected a generated ID, got %q", contextID)
+			}
+		})
+	}
+}
+
 func TestErrorTypes(t *testing.T) {
 	// Test that all error types are defined correctly
 	errorTypes := []ErrorType{
@@ -1057,6 +1507,8 @@ func TestErrorTypes(t *testing.T) {
 		ErrorTypeInternal,
 		ErrorTypeNotFound,
 		ErrorTypeUnauthorized,
+		ErrorTypeRateLimited,
+		ErrorTypeTooManyRedirects,
 	}
 
 	expectedTypes := []string{
@@ -1066,6 +1518,8 @@ func TestErrorTypes(t *testing.T) {
 		"internal_error",
 		"not_found_error",
 		"unauthorized_error",
+		"rate_limit_error",
+		"too_many_redirects_error",
 	}
 
 	for i, errorType := range errorTypes {
@@ -1114,4 +1568,60 @@ func TestMiddlewareChain(t *testing.T) {
 	if requestID := rr.Header().Get("X-Request-ID"); requestID == "" {
 		t.Error("X-Request-ID header should be set by middleware")
 	}
-}
\ No newline at end of file
+}
+func TestSanitizeUTF8(t *testing.T) {
+	valid := []byte("<title>Café</title>")
+	if got := sanitizeUTF8(valid); &got[0] != &valid[0] {
+		t.Error("expected valid UTF-8 to be returned without copying")
+	}
+
+	got := sanitizeUTF8([]byte("<title>Caf\xc3</title>\xff\xfe"))
+	if want := "<title>Caf�</title>�"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestAnalyzePageInvalidUTF8(t *testing.T) {
+	body := []byte("<html><head><title>Page not found \xe2\x82</title>" +
+		`<script src="https://www.google-analytics.com/analytics.js"></script></head>` +
+		"<body>\xff\xfe\x00\x80 binary \xc0\xaf</body></html>")
+	page := &fetchedPage{
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Body:       body,
+		StatusCode: http.StatusOK,
+	}
+
+	result, apiErr := analyzePage("test-request-id", AnalyzeRequest{URL: "https://example.com"}, ScanOptions{}, page)
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %v", apiErr.Message)
+	}
+	if !result.Soft404 {
+		t.Error("expected the soft 404 check to still match the title")
+	}
+	if result.Privacy == nil || result.Privacy.TrackerCount != 1 {
+		t.Errorf("expected one tracker despite invalid bytes, got %+v", result.Privacy)
+	}
+}
+
+func TestAnalyzePageTimings(t *testing.T) {
+	page := &fetchedPage{
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Body:       []byte("<html><head><title>Timings</title></head></html>"),
+		StatusCode: http.StatusOK,
+		FetchTime:  1500 * time.Microsecond,
+	}
+
+	result, apiErr := analyzePage("test-request-id", AnalyzeRequest{URL: "https://example.com"}, ScanOptions{}, page)
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %v", apiErr.Message)
+	}
+
+	for _, name := range []string{"technologies", "privacy", "soft_404", "keywords", "accessibility", "performance", "seo", "challenge", "redirects", "cookies", "cors", "version_disclosure"} {
+		if d, ok := result.Timings[name]; !ok || d < 0 {
+			t.Errorf("expected a timing for %s, got %v", name, result.Timings)
+		}
+	}
+	if result.Timings["fetch"] != 1.5 {
+		t.Errorf("expected fetch timing of 1.5ms, got %v", result.Timings["fetch"])
+	}
+}
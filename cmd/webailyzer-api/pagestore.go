@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when no blob exists for a key
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore persists opaque blobs by key; implementations must be safe for concurrent use
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// pageStore holds raw fetched pages for re-analysis; nil disables storage
+var pageStore BlobStore
+
+// analysisIDPattern matches IDs produced by newAnalysisID, keeping keys safe to use as file names
+var analysisIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// storedPage is the persisted form of a fetchedPage, enough to rerun the analysis
+type storedPage struct {
+	URL        string        `json:"url"`
+	FinalURL   string        `json:"final_url,omitempty"`
+	StatusCode int           `json:"status_code"`
+	Header     http.Header   `json:"header"`
+	Body       []byte        `json:"body"`
+	Redirects  []string      `json:"redirects,omitempty"`
+	Hops       []RedirectHop `json:"hops,omitempty"`
+}
+
+// newAnalysisID returns a random identifier for a stored page
+func newAnalysisID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// savePage stores the page under a new analysis ID. Pages whose body exceeds
+// maxBytes are not stored and yield an empty ID.
+func savePage(ctx context.Context, store BlobStore, maxBytes int64, url string, page *fetchedPage) (string, error) {
+	if int64(len(page.Body)) > maxBytes {
+		return "", nil
+	}
+
+	id, err := newAnalysisID()
+	if err != nil {
+		return "", fmt.Errorf("generating analysis ID: %w", err)
+	}
+
+	data, err := json.Marshal(storedPage{
+		URL:        url,
+		FinalURL:   page.FinalURL,
+		StatusCode: page.StatusCode,
+		Header:     page.Header,
+		Body:       page.Body,
+		Redirects:  page.Redirects,
+		Hops:       page.Hops,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding stored page: %w", err)
+	}
+
+	if err := store.Put(ctx, id, data); err != nil {
+		return "", fmt.Errorf("storing page: %w", err)
+	}
+	return id, nil
+}
+
+// loadPage reads a stored page back, returning ErrBlobNotFound for unknown or malformed IDs
+func loadPage(ctx context.Context, store BlobStore, id string) (string, *fetchedPage, error) {
+	if !analysisIDPattern.MatchString(id) {
+		return "", nil, ErrBlobNotFound
+	}
+
+	data, err := store.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var stored storedPage
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return "", nil, fmt.Errorf("decoding stored page: %w", err)
+	}
+
+	return stored.URL, &fetchedPage{
+		Header:     stored.Header,
+		Body:       stored.Body,
+		FinalURL:   stored.FinalURL,
+		StatusCode: stored.StatusCode,
+		Redirects:  stored.Redirects,
+		Hops:       stored.Hops,
+	}, nil
+}
+
+// dirBlobStore keeps each blob as a file in a directory
+type dirBlobStore struct {
+	dir string
+}
+
+// newDirBlobStore creates the directory if needed and returns a store backed by it
+func newDirBlobStore(dir string) (*dirBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating page store directory: %w", err)
+	}
+	return &dirBlobStore{dir: dir}, nil
+}
+
+func (s *dirBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	// Write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, key))
+}
+
+func (s *dirBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+// reanalyzeHandler reruns the analysis over a stored page without fetching it again.
+// The optional JSON body accepts the scan options of AnalyzeRequest; url and html are not allowed.
+func reanalyzeHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := ""
+	if id := r.Context().Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	if pageStore == nil {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeNotFound,
+			Message:    "Page storage is disabled",
+			Details:    "Set PAGE_STORE_DIR to keep fetched pages for re-analysis",
+			StatusCode: http.StatusNotFound,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	var req AnalyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid JSON format",
+			Details:    "Request body must be valid JSON",
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		})
+		return
+	}
+	if req.URL != "" || req.HTML != "" {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid re-analysis request",
+			Details:    "url and html cannot be set when re-analyzing a stored page",
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	opts, err := resolveScanOptions(req)
+	if err != nil {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeValidation,
+			Message:    "Invalid scan options",
+			Details:    err.Error(),
+			StatusCode: http.StatusBadRequest,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	analysisID := mux.Vars(r)["id"]
+	url, page, err := loadPage(r.Context(), pageStore, analysisID)
+	if errors.Is(err, ErrBlobNotFound) {
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeNotFound,
+			Message:    "Stored page not found",
+			Details:    fmt.Sprintf("No stored page for analysis %q", analysisID),
+			StatusCode: http.StatusNotFound,
+			RequestID:  requestID,
+		})
+		return
+	}
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id":  requestID,
+			"analysis_id": analysisID,
+			"error":       err,
+		}).Error("Failed to load stored page")
+
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeInternal,
+			Message:    "Failed to load stored page",
+			Details:    "Error occurred while reading the page store",
+			StatusCode: http.StatusInternalServerError,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"request_id":  requestID,
+		"analysis_id": analysisID,
+		"url":         url,
+	}).Info("Re-analyzing stored page")
+
+	req.URL = url
+	result, apiErr := analyzePage(requestID, req, opts, page)
+	if apiErr != nil {
+		sendErrorResponse(w, *apiErr)
+		return
+	}
+	result.AnalysisID = analysisID
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to encode analysis response")
+	}
+}
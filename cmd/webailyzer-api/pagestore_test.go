@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// memoryBlobStore is an in-memory BlobStore for tests
+type memoryBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func newMemoryBlobStore() *memoryBlobStore {
+	return &memoryBlobStore{blobs: make(map[string][]byte)}
+}
+
+func (s *memoryBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memoryBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return data, nil
+}
+
+func withPageStore(t *testing.T, store BlobStore) {
+	t.Helper()
+	original := pageStore
+	pageStore = store
+	t.Cleanup(func() { pageStore = original })
+}
+
+func postReanalyze(t *testing.T, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, err := http.NewRequest("POST", "/v1/analyses/"+id+"/reanalyze", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/analyses/{id}/reanalyze", reanalyzeHandler).Methods("POST")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAnalyzeHandlerStoresPageForReanalysis(t *testing.T) {
+	store := newMemoryBlobStore()
+	withPageStore(t, store)
+
+	rr := postAnalyze(t, map[string]string{"html": inlineTestHTML})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var analyzed AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &analyzed); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if analyzed.AnalysisID == "" {
+		t.Fatal("expected an analysis_id when the page store is enabled")
+	}
+	if len(store.blobs) != 1 {
+		t.Fatalf("expected one stored page, got %d", len(store.blobs))
+	}
+
+	rr = postReanalyze(t, analyzed.AnalysisID, `{"include_headers": true}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var reanalyzed AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &reanalyzed); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if reanalyzed.AnalysisID != analyzed.AnalysisID {
+		t.Errorf("expected analysis_id %q, got %q", analyzed.AnalysisID, reanalyzed.AnalysisID)
+	}
+	if _, ok := reanalyzed.Detected["PHP"]; !ok {
+		t.Errorf("expected the stored page to be re-analyzed, got %v", reanalyzed.Detected)
+	}
+	if reanalyzed.ContentType != inlineContentType {
+		t.Errorf("expected stored content type %q, got %q", inlineContentType, reanalyzed.ContentType)
+	}
+	if len(reanalyzed.Headers) == 0 {
+		t.Error("expected include_headers to apply to the re-analysis")
+	}
+}
+
+func TestAnalyzeHandlerSkipsOversizedPages(t *testing.T) {
+	store := newMemoryBlobStore()
+	withPageStore(t, store)
+
+	original := appConfig
+	appConfig.PageStoreMaxBytes = int64(len(inlineTestHTML) - 1)
+	defer func() { appConfig = original }()
+
+	rr := postAnalyze(t, map[string]string{"html": inlineTestHTML})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.AnalysisID != "" || len(store.blobs) != 0 {
+		t.Errorf("expected a page over PAGE_STORE_MAX_BYTES not to be stored, got ID %q", response.AnalysisID)
+	}
+}
+
+func TestReanalyzeHandlerErrors(t *testing.T) {
+	withPageStore(t, nil)
+	if rr := postReanalyze(t, strings.Repeat("a", 32), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 with storage disabled, got %d", rr.Code)
+	}
+
+	withPageStore(t, newMemoryBlobStore())
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+	}{
+		{"unknown ID", strings.Repeat("a", 32), "", http.StatusNotFound},
+		{"malformed ID", "not-an-id", "", http.StatusNotFound},
+		{"url not allowed", strings.Repeat("a", 32), `{"url": "https://example.com"}`, http.StatusBadRequest},
+		{"invalid JSON", strings.Repeat("a", 32), `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postReanalyze(t, tt.id, tt.body)
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDirBlobStore(t *testing.T) {
+	store, err := newDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound, got %v", err)
+	}
+
+	if err := store.Put(ctx, "page", []byte("<html></html>")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := store.Get(ctx, "page")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, []byte("<html></html>")) {
+		t.Errorf("expected stored blob back, got %q", data)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Optimization suggestions reported by analyzePerformance
+const (
+	perfSuggestionMissingViewport = "missing_viewport"
+	perfSuggestionFixedViewport   = "fixed_width_viewport"
+	perfSuggestionZoomDisabled    = "zoom_disabled"
+	perfSuggestionUnsizedImages   = "unsized_images"
+)
+
+// unsizedImagesHighRisk is the number of images without dimensions at which layout
+// shift risk is high
+const unsizedImagesHighRisk = 4
+
+// Layout shift risk levels, estimated from images without dimensions
+const (
+	layoutShiftLow    = "low"
+	layoutShiftMedium = "medium"
+	layoutShiftHigh   = "high"
+)
+
+// PerformanceReport describes how the page's markup affects loading and rendering,
+// on mobile devices in particular
+type PerformanceReport struct {
+	// Viewport is the content of the page's viewport meta tag
+	Viewport string `json:"viewport,omitempty"`
+	// MobileReady is set when the viewport fits the device width and allows zooming
+	MobileReady bool `json:"mobile_ready"`
+	Images      int  `json:"images"`
+	// UnsizedImages counts images that give the browser no size to reserve before
+	// they load: no width and height attributes and no inline aspect-ratio
+	UnsizedImages int `json:"unsized_images"`
+	// LayoutShiftRisk estimates Cumulative Layout Shift from UnsizedImages: low, medium or high
+	LayoutShiftRisk string                   `json:"layout_shift_risk"`
+	Suggestions     []OptimizationSuggestion `json:"suggestions"`
+}
+
+// OptimizationSuggestion is one change that would make the page faster or more usable
+type OptimizationSuggestion struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Elements quotes up to snippetLimit offending start tags
+	Elements []string `json:"elements,omitempty"`
+}
+
+// snippetLimit caps the elements quoted in a suggestion
+const snippetLimit = 5
+
+// performanceMarkup is what analyzePerformance reads from the page
+type performanceMarkup struct {
+	Viewport    string
+	HasViewport bool
+	Images      int
+	// Unsized quotes the images without dimensions
+	Unsized []string
+}
+
+// scanPerformanceMarkup tokenizes the page for the tags that affect rendering
+func scanPerformanceMarkup(body []byte) performanceMarkup {
+	var markup performanceMarkup
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF at the end of the body; the tokenizer recovers from malformed markup itself
+			return markup
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// Raw must be read before TagName, which lowercases the name in place
+			raw := string(z.Raw())
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "meta":
+				// Browsers apply the first viewport tag
+				if markup.HasViewport {
+					continue
+				}
+				if attrs := tagAttrs(z, hasAttr); strings.EqualFold(attrs["name"], "viewport") {
+					markup.Viewport, markup.HasViewport = attrs["content"], true
+				}
+			case "img":
+				markup.Images++
+				if !hasDimensions(tagAttrs(z, hasAttr)) {
+					markup.Unsized = append(markup.Unsized, elementSnippet(raw))
+				}
+			}
+		}
+	}
+}
+
+// analyzePerformance checks the page markup for mobile readiness and for images
+// likely to shift the layout as they load
+func analyzePerformance(body []byte) *PerformanceReport {
+	markup := scanPerformanceMarkup(body)
+
+	report := &PerformanceReport{
+		Viewport:        markup.Viewport,
+		Images:          markup.Images,
+		UnsizedImages:   len(markup.Unsized),
+		LayoutShiftRisk: layoutShiftRisk(len(markup.Unsized)),
+		Suggestions:     []OptimizationSuggestion{},
+	}
+	mobile := viewportSuggestions(markup.Viewport, markup.HasViewport)
+	report.MobileReady = len(mobile) == 0
+	report.Suggestions = append(report.Suggestions, mobile...)
+
+	if unsized := len(markup.Unsized); unsized > 0 {
+		severity := "medium"
+		if report.LayoutShiftRisk == layoutShiftHigh {
+			severity = "high"
+		}
+		elements := markup.Unsized
+		if len(elements) > snippetLimit {
+			elements = elements[:snippetLimit]
+		}
+		report.Suggestions = append(report.Suggestions, OptimizationSuggestion{
+			Type:     perfSuggestionUnsizedImages,
+			Severity: severity,
+			Message:  fmt.Sprintf("%d of %d images have no width and height, so content shifts as they load; set both attributes or an aspect-ratio", unsized, markup.Images),
+			Elements: elements,
+		})
+	}
+	return report
+}
+
+// hasDimensions reports whether an image lets the browser reserve its space before
+// it loads, through width and height attributes or an inline aspect-ratio or size
+func hasDimensions(attrs map[string]string) bool {
+	if strings.TrimSpace(attrs["width"]) != "" && strings.TrimSpace(attrs["height"]) != "" {
+		return true
+	}
+	style := make(map[string]bool)
+	for _, declaration := range strings.Split(strings.ToLower(attrs["style"]), ";") {
+		if property, _, ok := strings.Cut(declaration, ":"); ok {
+			style[strings.TrimSpace(property)] = true
+		}
+	}
+	return style["aspect-ratio"] || (style["width"] && style["height"])
+}
+
+// layoutShiftRisk estimates how much the layout shifts as images without dimensions load
+func layoutShiftRisk(unsized int) string {
+	switch {
+	case unsized == 0:
+		return layoutShiftLow
+	case unsized < unsizedImagesHighRisk:
+		return layoutShiftMedium
+	default:
+		return layoutShiftHigh
+	}
+}
+
+// viewportSuggestions checks that the viewport scales the page to the device width
+// and leaves zooming to the visitor
+func viewportSuggestions(viewport string, found bool) []OptimizationSuggestion {
+	if !found {
+		return []OptimizationSuggestion{{
+			Type:     perfSuggestionMissingViewport,
+			Severity: "high",
+			Message:  `The page has no viewport meta tag, so mobile browsers render it at desktop width; add <meta name="viewport" content="width=device-width, initial-scale=1">`,
+		}}
+	}
+
+	settings := make(map[string]string)
+	for _, part := range strings.FieldsFunc(strings.ToLower(viewport), func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, _ := strings.Cut(part, "=")
+		settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	var suggestions []OptimizationSuggestion
+	if settings["width"] != "device-width" {
+		suggestions = append(suggestions, OptimizationSuggestion{
+			Type:     perfSuggestionFixedViewport,
+			Severity: "medium",
+			Message:  "The viewport does not set width=device-width, so the page does not adapt to the screen it is shown on",
+		})
+	}
+	scale, err := strconv.ParseFloat(settings["maximum-scale"], 64)
+	if scalable := settings["user-scalable"]; scalable == "no" || scalable == "0" || (err == nil && scale < 2) {
+		suggestions = append(suggestions, OptimizationSuggestion{
+			Type:     perfSuggestionZoomDisabled,
+			Severity: "medium",
+			Message:  "The viewport stops visitors from zooming to at least 200%; remove user-scalable=no and any maximum-scale below 2",
+		})
+	}
+	return suggestions
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// suggestionTypes returns the suggestion types in report order
+func suggestionTypes(report *PerformanceReport) []string {
+	types := []string{}
+	for _, s := range report.Suggestions {
+		types = append(types, s.Type)
+	}
+	return types
+}
+
+func TestAnalyzePerformanceMobileReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		head   string
+		ready  bool
+		issues []string
+	}{
+		{name: "mobile ready", head: `<meta name="viewport" content="width=device-width, initial-scale=1">`, ready: true, issues: []string{}},
+		{name: "zoom limited to 5x", head: `<META NAME="Viewport" CONTENT="width=device-width; maximum-scale=5">`, ready: true, issues: []string{}},
+		{name: "no viewport", head: `<meta name="description" content="Bakery">`, issues: []string{perfSuggestionMissingViewport}},
+		{name: "fixed width", head: `<meta name="viewport" content="width=1024">`, issues: []string{perfSuggestionFixedViewport}},
+		{name: "zoom disabled", head: `<meta name="viewport" content="width=device-width, user-scalable=no">`, issues: []string{perfSuggestionZoomDisabled}},
+		{name: "zoom capped", head: `<meta name="viewport" content="width=device-width, maximum-scale=1.0">`, issues: []string{perfSuggestionZoomDisabled}},
+		{
+			name:   "first viewport wins",
+			head:   `<meta name="viewport" content="width=980"><meta name="viewport" content="width=device-width">`,
+			issues: []string{perfSuggestionFixedViewport},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzePerformance([]byte(`<html><head>` + tt.head + `</head><body></body></html>`))
+			if report.MobileReady != tt.ready {
+				t.Errorf("expected mobile_ready %v, got %+v", tt.ready, report)
+			}
+			if got := suggestionTypes(report); !reflect.DeepEqual(got, tt.issues) {
+				t.Errorf("expected suggestions %v, got %+v", tt.issues, report.Suggestions)
+			}
+		})
+	}
+}
+
+func TestAnalyzePerformanceLayoutShift(t *testing.T) {
+	viewport := `<meta name="viewport" content="width=device-width">`
+	tests := []struct {
+		name    string
+		images  string
+		unsized int
+		risk    string
+	}{
+		{name: "no images", risk: layoutShiftLow},
+		{
+			name:   "dimensioned images",
+			images: `<img src="a.jpg" width="640" height="480"><img src="b.jpg" style="aspect-ratio: 4 / 3; width: 100%"><img src="c.jpg" style="width:10px;height:10px">`,
+			risk:   layoutShiftLow,
+		},
+		{
+			name:    "one undimensioned image",
+			images:  `<img src="a.jpg" width="640" height="480"><img src="hero.jpg" width="640">`,
+			unsized: 1,
+			risk:    layoutShiftMedium,
+		},
+		{
+			name:    "many undimensioned images",
+			images:  strings.Repeat(`<img src="gallery.jpg">`, 6),
+			unsized: 6,
+			risk:    layoutShiftHigh,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzePerformance([]byte(`<html><head>` + viewport + `</head><body>` + tt.images + `</body></html>`))
+			if report.UnsizedImages != tt.unsized || report.LayoutShiftRisk != tt.risk {
+				t.Errorf("expected %d unsized images and %s risk, got %d and %s", tt.unsized, tt.risk, report.UnsizedImages, report.LayoutShiftRisk)
+			}
+			if !report.MobileReady {
+				t.Error("expected images not to affect mobile readiness")
+			}
+			if tt.unsized == 0 {
+				if len(report.Suggestions) != 0 {
+					t.Errorf("expected no suggestions, got %+v", report.Suggestions)
+				}
+				return
+			}
+			if len(report.Suggestions) != 1 || report.Suggestions[0].Type != perfSuggestionUnsizedImages {
+				t.Fatalf("expected an unsized_images suggestion, got %+v", report.Suggestions)
+			}
+			if elements := report.Suggestions[0].Elements; len(elements) != min(tt.unsized, snippetLimit) || !strings.HasPrefix(elements[0], "<img src=") {
+				t.Errorf("expected the unsized images quoted, got %v", elements)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerReportsPerformance(t *testing.T) {
+	rr := runPipeline(t, map[string]interface{}{
+		"html":      `<html><head><meta name="viewport" content="width=device-width"></head><body></body></html>`,
+		"analyzers": []string{"performance"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Performance == nil || !response.Performance.MobileReady || response.Performance.Viewport != "width=device-width" {
+		t.Errorf("expected a mobile ready page, got %+v", response.Performance)
+	}
+}
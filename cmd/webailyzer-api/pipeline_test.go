@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFixtureServer serves offline fixtures covering the shapes of site the analyzer meets in practice
+func newFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/wordpress", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Powered-By", "PHP/8.2")
+		w.Header().Set("Link", `<https://example.com/wp-json/>; rel="https://api.w.org/"`)
+		w.Header().Add("Set-Cookie", "PHPSESSID=abc; Path=/")
+		w.Write([]byte(`<html><head>
+<meta name="generator" content="WordPress 6.4.2">
+<link rel="stylesheet" href="/wp-content/themes/twentytwentyfour/style.css">
+<script src="https://www.googletagmanager.com/gtag/js?id=G-TEST"></script>
+<script src="https://cdn.cookielaw.org/scripttemplates/otSDKStub.js"></script>
+<title>Example Blog</title></head>
+<body><article>` + strings.Repeat("Words about the blog. ", 100) + `</article></body></html>`))
+	})
+	mux.HandleFunc("/spa", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>App</title>
+<script src="/static/js/react.production.min.js"></script></head>
+<body><div id="root" data-reactroot=""></div></body></html>`))
+	})
+	mux.HandleFunc("/secure", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "nginx")
+		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		w.Header().Set("Access-Control-Allow-Origin", "https://app.example.com")
+		w.Header().Add("Set-Cookie", "session_id=abc; Path=/; Secure; HttpOnly; SameSite=Lax")
+		w.Write([]byte(`<html><head><title>Secure</title></head><body>` + strings.Repeat("Hardened content. ", 50) + `</body></html>`))
+	})
+	mux.HandleFunc("/misconfigured", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("X-AspNet-Version", "4.0.30319")
+		w.Header().Add("Set-Cookie", "session_id=abc; Path=/")
+		w.Write([]byte(`<html><head><title>Misconfigured</title></head><body>` + strings.Repeat("Open content. ", 50) + `</body></html>`))
+	})
+	mux.HandleFunc("/soft-404", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page Not Found</title></head><body>Sorry, we could not find that page.</body></html>`))
+	})
+	mux.HandleFunc("/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+			w.Write([]byte(`<html></html>`))
+		case <-r.Context().Done():
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runPipeline sends the request through the middleware chain and analyze handler, as the server does
+func runPipeline(t *testing.T, body map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	requestBody, _ := json.Marshal(body)
+	req, err := http.NewRequest("POST", "/v1/analyze", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	errorHandlingMiddleware(loggingMiddleware(http.HandlerFunc(analyzeHandler))).ServeHTTP(rr, req)
+	return rr
+}
+
+// hasTechnology reports whether name was detected, with or without a version suffix
+func hasTechnology(detected map[string]interface{}, name string) bool {
+	for key := range detected {
+		if key == name || strings.HasPrefix(key, name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFinding(findings []SecurityFinding, findingType string) bool {
+	for _, f := range findings {
+		if f.Type == findingType {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPipelineFixtures(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		name  string
+		path  string
+		check func(t *testing.T, r AnalyzeResponse)
+	}{
+		{"wordpress site", "/wordpress", func(t *testing.T, r AnalyzeResponse) {
+			for _, tech := range []string{"WordPress", "PHP"} {
+				if !hasTechnology(r.Detected, tech) {
+					t.Errorf("expected %s to be detected, got %v", tech, r.Detected)
+				}
+			}
+			if r.Privacy == nil || r.Privacy.TrackerCount == 0 {
+				t.Errorf("expected the Google tag to be reported as a tracker, got %+v", r.Privacy)
+			}
+			if r.Privacy != nil && !r.Privacy.Consent.Detected {
+				t.Error("expected the OneTrust consent platform to be detected")
+			}
+			if !hasFinding(r.SecurityFindings, "insecure_session_cookie") {
+				t.Errorf("expected the PHP session cookie without Secure/HttpOnly to be flagged, got %v", r.SecurityFindings)
+			}
+			if r.Soft404 {
+				t.Error("a real blog page should not be flagged as a soft 404")
+			}
+		}},
+		{"react spa", "/spa", func(t *testing.T, r AnalyzeResponse) {
+			if !hasTechnology(r.Detected, "React") {
+				t.Errorf("expected React to be detected, got %v", r.Detected)
+			}
+		}},
+		{"security headers", "/secure", func(t *testing.T, r AnalyzeResponse) {
+			for _, tech := range []string{"HSTS", "Nginx"} {
+				if !hasTechnology(r.Detected, tech) {
+					t.Errorf("expected %s to be detected, got %v", tech, r.Detected)
+				}
+			}
+			if len(r.SecurityFindings) != 0 {
+				t.Errorf("expected no security findings, got %v", r.SecurityFindings)
+			}
+			if len(r.Cookies) != 1 || len(r.Cookies[0].Issues) != 0 {
+				t.Errorf("expected one cookie without issues, got %+v", r.Cookies)
+			}
+			if r.ResponseHeaders["Server"] != "nginx" {
+				t.Errorf("expected the Server header to be echoed, got %v", r.ResponseHeaders)
+			}
+		}},
+		{"misconfigured security", "/misconfigured", func(t *testing.T, r AnalyzeResponse) {
+			for _, finding := range []string{"insecure_session_cookie", "cors_wildcard_with_credentials", "information_disclosure"} {
+				if !hasFinding(r.SecurityFindings, finding) {
+					t.Errorf("expected a %s finding, got %v", finding, r.SecurityFindings)
+				}
+			}
+		}},
+		{"soft 404", "/soft-404", func(t *testing.T, r AnalyzeResponse) {
+			if !r.Soft404 || r.Soft404Indicator == "" {
+				t.Errorf("expected a soft 404 with an indicator, got %v %q", r.Soft404, r.Soft404Indicator)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := runPipeline(t, map[string]interface{}{"url": server.URL + tt.path})
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if rr.Header().Get("X-Request-ID") == "" {
+				t.Error("expected the pipeline to set X-Request-ID")
+			}
+
+			var response AnalyzeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if response.StatusCode != http.StatusOK || response.FinalURL != server.URL+tt.path {
+				t.Errorf("expected a 200 from %s, got %d from %s", tt.path, response.StatusCode, response.FinalURL)
+			}
+			if _, ok := response.Timings["fetch"]; !ok {
+				t.Errorf("expected a fetch timing, got %v", response.Timings)
+			}
+			tt.check(t, response)
+		})
+	}
+}
+
+func TestPipelineFixtureErrors(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		name      string
+		body      map[string]interface{}
+		status    int
+		errorType ErrorType
+	}{
+		{"inaccessible page", map[string]interface{}{"url": server.URL + "/forbidden"}, http.StatusForbidden, ErrorTypeUnauthorized},
+		{"missing page", map[string]interface{}{"url": server.URL + "/nothing-here"}, http.StatusNotFound, ErrorTypeNotFound},
+		{"slow page", map[string]interface{}{"url": server.URL + "/slow", "timeout_ms": 200}, http.StatusGatewayTimeout, ErrorTypeTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			rr := runPipeline(t, tt.body)
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("expected the request to finish within its deadline, took %v", elapsed)
+			}
+
+			var response ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if response.Type != tt.errorType {
+				t.Errorf("expected error type %s, got %s", tt.errorType, response.Type)
+			}
+			if response.RequestID == "" || response.RequestID != rr.Header().Get("X-Request-ID") {
+				t.Errorf("expected the error to carry the request ID %q, got %q", rr.Header().Get("X-Request-ID"), response.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Tracker purposes reported by the privacy analyzer
+const (
+	TrackerPurposeAnalytics   = "analytics"
+	TrackerPurposeAdvertising = "advertising"
+	TrackerPurposeSocial      = "social"
+)
+
+// TrackerSignature identifies a third-party tracker by the host it loads from.
+// Host matches the host and its subdomains; an optional path prefix narrows the
+// match for hosts that serve more than one kind of script.
+type TrackerSignature struct {
+	Host    string `json:"host"`
+	Path    string `json:"path,omitempty"`
+	Name    string `json:"name"`
+	Purpose string `json:"purpose"`
+}
+
+// Tracker is a third-party tracker detected on a page
+type Tracker struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+}
+
+// ConsentSignature identifies a consent-management platform (CMP) by
+// case-insensitive substrings of its embed code, such as script hosts and globals
+type ConsentSignature struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+}
+
+// ConsentReport describes whether a page appears to ask for cookie consent
+type ConsentReport struct {
+	Detected     bool     `json:"detected"`
+	Platforms    []string `json:"platforms"`
+	BannerMarkup bool     `json:"banner_markup"`
+}
+
+// PrivacyReport lists the trackers a page loads, grouped by purpose, and any consent mechanism
+type PrivacyReport struct {
+	TrackerCount int                  `json:"tracker_count"`
+	Trackers     map[string][]Tracker `json:"trackers"`
+	Consent      ConsentReport        `json:"consent"`
+}
+
+// PrivacyAnalyzer detects third-party trackers from the script and pixel URLs a
+// page loads, and consent-management platforms from its markup
+type PrivacyAnalyzer struct {
+	signatures []TrackerSignature
+	consent    []ConsentSignature
+}
+
+// privacyAnalyzer is the active analyzer, extended at startup from TRACKERS_FILE
+// and CONSENT_SIGNATURES_FILE
+var privacyAnalyzer = NewPrivacyAnalyzer(defaultTrackerSignatures(), defaultConsentSignatures())
+
+// NewPrivacyAnalyzer creates an analyzer for the given tracker and consent signatures
+func NewPrivacyAnalyzer(signatures []TrackerSignature, consent []ConsentSignature) *PrivacyAnalyzer {
+	return &PrivacyAnalyzer{signatures: signatures, consent: consent}
+}
+
+// defaultTrackerSignatures returns the built-in tracker list
+func defaultTrackerSignatures() []TrackerSignature {
+	return []TrackerSignature{
+		{Host: "google-analytics.com", Name: "Google Analytics", Purpose: TrackerPurposeAnalytics},
+		{Host: "analytics.google.com", Name: "Google Analytics", Purpose: TrackerPurposeAnalytics},
+		{Host: "googletagmanager.com", Name: "Google Tag Manager", Purpose: TrackerPurposeAnalytics},
+		{Host: "hotjar.com", Name: "Hotjar", Purpose: TrackerPurposeAnalytics},
+		{Host: "clarity.ms", Name: "Microsoft Clarity", Purpose: TrackerPurposeAnalytics},
+		{Host: "cdn.segment.com", Name: "Segment", Purpose: TrackerPurposeAnalytics},
+		{Host: "cdn.mxpnl.com", Name: "Mixpanel", Purpose: TrackerPurposeAnalytics},
+		{Host: "cdn.amplitude.com", Name: "Amplitude", Purpose: TrackerPurposeAnalytics},
+		{Host: "heapanalytics.com", Name: "Heap", Purpose: TrackerPurposeAnalytics},
+		{Host: "fullstory.com", Name: "FullStory", Purpose: TrackerPurposeAnalytics},
+		{Host: "plausible.io", Name: "Plausible", Purpose: TrackerPurposeAnalytics},
+		{Host: "doubleclick.net", Name: "DoubleClick", Purpose: TrackerPurposeAdvertising},
+		{Host: "googlesyndication.com", Name: "Google AdSense", Purpose: TrackerPurposeAdvertising},
+		{Host: "googleadservices.com", Name: "Google Ads", Purpose: TrackerPurposeAdvertising},
+		{Host: "facebook.com", Path: "/tr", Name: "Meta Pixel", Purpose: TrackerPurposeAdvertising},
+		{Host: "connect.facebook.net", Path: "/signals/", Name: "Meta Pixel", Purpose: TrackerPurposeAdvertising},
+		{Host: "connect.facebook.net", Name: "Facebook SDK", Purpose: TrackerPurposeSocial},
+		{Host: "amazon-adsystem.com", Name: "Amazon Advertising", Purpose: TrackerPurposeAdvertising},
+		{Host: "adnxs.com", Name: "Xandr", Purpose: TrackerPurposeAdvertising},
+		{Host: "criteo.com", Name: "Criteo", Purpose: TrackerPurposeAdvertising},
+		{Host: "criteo.net", Name: "Criteo", Purpose: TrackerPurposeAdvertising},
+		{Host: "taboola.com", Name: "Taboola", Purpose: TrackerPurposeAdvertising},
+		{Host: "outbrain.com", Name: "Outbrain", Purpose: TrackerPurposeAdvertising},
+		{Host: "bat.bing.com", Name: "Microsoft Advertising", Purpose: TrackerPurposeAdvertising},
+		{Host: "snap.licdn.com", Name: "LinkedIn Insight Tag", Purpose: TrackerPurposeAdvertising},
+		{Host: "static.ads-twitter.com", Name: "X Ads", Purpose: TrackerPurposeAdvertising},
+		{Host: "platform.twitter.com", Name: "X Widgets", Purpose: TrackerPurposeSocial},
+		{Host: "platform.linkedin.com", Name: "LinkedIn Widgets", Purpose: TrackerPurposeSocial},
+		{Host: "addthis.com", Name: "AddThis", Purpose: TrackerPurposeSocial},
+		{Host: "sharethis.com", Name: "ShareThis", Purpose: TrackerPurposeSocial},
+	}
+}
+
+// loadTrackerSignatures reads additional signatures from a JSON array and merges
+// them over the built-in list; an entry with the same host and path replaces the built-in one
+func loadTrackerSignatures(path string) ([]TrackerSignature, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tracker list: %w", err)
+	}
+
+	var custom []TrackerSignature
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parsing tracker list: %w", err)
+	}
+
+	for _, sig := range custom {
+		if sig.Host == "" || sig.Name == "" {
+			return nil, fmt.Errorf("tracker entries need a host and a name")
+		}
+		switch sig.Purpose {
+		case TrackerPurposeAnalytics, TrackerPurposeAdvertising, TrackerPurposeSocial:
+		default:
+			return nil, fmt.Errorf("tracker %q has unknown purpose %q", sig.Name, sig.Purpose)
+		}
+	}
+
+	// Custom entries go first so they take precedence when matching
+	signatures := custom
+	for _, builtin := range defaultTrackerSignatures() {
+		overridden := false
+		for _, sig := range custom {
+			if strings.EqualFold(sig.Host, builtin.Host) && sig.Path == builtin.Path {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			signatures = append(signatures, builtin)
+		}
+	}
+	return signatures, nil
+}
+
+// defaultConsentSignatures returns the built-in consent-management platform list
+func defaultConsentSignatures() []ConsentSignature {
+	return []ConsentSignature{
+		{Name: "OneTrust", Patterns: []string{"cdn.cookielaw.org", "optanon", "onetrust"}},
+		{Name: "Cookiebot", Patterns: []string{"consent.cookiebot.com", "cookiebot"}},
+		{Name: "Didomi", Patterns: []string{"sdk.privacy-center.org", "didomi"}},
+		{Name: "Quantcast Choice", Patterns: []string{"cmp.quantcast.com", "quantcast.mgr.consensu.org"}},
+		{Name: "TrustArc", Patterns: []string{"consent.trustarc.com", "truste-consent"}},
+		{Name: "Usercentrics", Patterns: []string{"app.usercentrics.eu", "usercentrics"}},
+		{Name: "Osano", Patterns: []string{"cmp.osano.com"}},
+		{Name: "CookieYes", Patterns: []string{"cdn-cookieyes.com"}},
+		{Name: "iubenda", Patterns: []string{"cdn.iubenda.com", "_iub.csConfiguration"}},
+		{Name: "Termly", Patterns: []string{"app.termly.io"}},
+		{Name: "Complianz", Patterns: []string{"cmplz-cookiebanner"}},
+		{Name: "Cookie Consent", Patterns: []string{"cookieconsent.min.js", "window.cookieconsent"}},
+	}
+}
+
+// loadConsentSignatures reads additional CMP signatures from a JSON array and
+// merges them over the built-in list; an entry with the same name replaces the built-in one
+func loadConsentSignatures(path string) ([]ConsentSignature, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading consent signatures: %w", err)
+	}
+
+	var custom []ConsentSignature
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parsing consent signatures: %w", err)
+	}
+
+	names := make(map[string]bool, len(custom))
+	for _, sig := range custom {
+		if sig.Name == "" || len(sig.Patterns) == 0 {
+			return nil, fmt.Errorf("consent signatures need a name and at least one pattern")
+		}
+		for _, pattern := range sig.Patterns {
+			if strings.TrimSpace(pattern) == "" {
+				return nil, fmt.Errorf("consent signature %q has an empty pattern", sig.Name)
+			}
+		}
+		names[sig.Name] = true
+	}
+
+	signatures := custom
+	for _, builtin := range defaultConsentSignatures() {
+		if !names[builtin.Name] {
+			signatures = append(signatures, builtin)
+		}
+	}
+	return signatures, nil
+}
+
+// consentBannerNamePattern matches id and class values commonly used for cookie banners
+var consentBannerNamePattern = regexp.MustCompile(`(?i)\b(?:cookie[-_]?(?:banner|consent|notice|bar|popup)|consent[-_]?(?:banner|popup|modal)|gdpr[-_]?(?:banner|consent|notice)|cc[-_]banner)`)
+
+// privacyMarkup is what the privacy report reads from the page markup
+type privacyMarkup struct {
+	ResourceSrcs []string // src attributes of script and img elements, in document order
+	BannerMarkup bool     // some element's id or class looks like a cookie banner
+}
+
+// scanPrivacyMarkup tokenizes the page so that attributes in any order, spanning lines
+// or containing '>' are read correctly and commented-out markup is ignored. <noscript>
+// content is read as markup, since tracking pixels are commonly placed there.
+func scanPrivacyMarkup(body []byte) privacyMarkup {
+	var markup privacyMarkup
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF at the end of the body; the tokenizer recovers from malformed markup itself
+			return markup
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			if tag == "noscript" {
+				z.NextIsNotRawText()
+			}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "src":
+					if tag == "script" || tag == "img" {
+						markup.ResourceSrcs = append(markup.ResourceSrcs, string(value))
+					}
+				case "id", "class":
+					if !markup.BannerMarkup && consentBannerNamePattern.Match(value) {
+						markup.BannerMarkup = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// Analyze reports the trackers loaded by the page body
+func (a *PrivacyAnalyzer) Analyze(body []byte) *PrivacyReport {
+	report := &PrivacyReport{
+		Trackers: map[string][]Tracker{
+			TrackerPurposeAnalytics:   {},
+			TrackerPurposeAdvertising: {},
+			TrackerPurposeSocial:      {},
+		},
+	}
+
+	markup := scanPrivacyMarkup(body)
+
+	seen := make(map[string]bool)
+	for _, src := range markup.ResourceSrcs {
+		sig, host, ok := a.match(src)
+		if !ok || seen[sig.Name] {
+			continue
+		}
+		seen[sig.Name] = true
+		report.Trackers[sig.Purpose] = append(report.Trackers[sig.Purpose], Tracker{Name: sig.Name, Host: host})
+		report.TrackerCount++
+	}
+
+	for _, trackers := range report.Trackers {
+		sort.Slice(trackers, func(i, j int) bool { return trackers[i].Name < trackers[j].Name })
+	}
+
+	report.Consent = a.analyzeConsent(body, markup.BannerMarkup)
+	return report
+}
+
+// analyzeConsent looks for known CMP embed code, combined with whether the markup
+// has a generic consent banner
+func (a *PrivacyAnalyzer) analyzeConsent(body []byte, bannerMarkup bool) ConsentReport {
+	consent := ConsentReport{Platforms: []string{}}
+
+	lower := strings.ToLower(string(body))
+	for _, sig := range a.consent {
+		for _, pattern := range sig.Patterns {
+			if strings.Contains(lower, strings.ToLower(pattern)) {
+				consent.Platforms = append(consent.Platforms, sig.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(consent.Platforms)
+
+	consent.BannerMarkup = bannerMarkup
+	consent.Detected = len(consent.Platforms) > 0 || consent.BannerMarkup
+	return consent
+}
+
+// match returns the first signature matching an absolute or protocol-relative
+// resource URL; relative URLs are first-party and never match
+func (a *PrivacyAnalyzer) match(src string) (TrackerSignature, string, bool) {
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil || u.Hostname() == "" {
+		return TrackerSignature{}, "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, sig := range a.signatures {
+		sigHost := strings.ToLower(sig.Host)
+		if host != sigHost && !strings.HasSuffix(host, "."+sigHost) {
+			continue
+		}
+		if sig.Path != "" && !strings.HasPrefix(u.Path, sig.Path) {
+			continue
+		}
+		return sig, host, true
+	}
+	return TrackerSignature{}, "", false
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// trackingTestHTML loads Google Analytics via gtag and the standard Meta Pixel snippet
+const trackingTestHTML = `<html><head>
+<script async src="https://www.googletagmanager.com/gtag/js?id=G-TEST123"></script>
+<script src='//www.google-analytics.com/analytics.js'></script>
+<script>
+!function(f,b,e,v,n,t,s){t=b.createElement(e);t.src=v;s=b.getElementsByTagName(e)[0];
+s.parentNode.insertBefore(t,s)}(window,document,'script','https://connect.facebook.net/en_US/fbevents.js');
+fbq('init', '1234567890');
+</script>
+<noscript><img height="1" width="1" style="display:none" src="https://www.facebook.com/tr?id=1234567890&ev=PageView&noscript=1"/></noscript>
+<script src="/static/app.js"></script>
+</head><body></body></html>`
+
+func trackerNames(trackers []Tracker) []string {
+	names := make([]string, len(trackers))
+	for i, tracker := range trackers {
+		names[i] = tracker.Name
+	}
+	return names
+}
+
+func TestPrivacyAnalyzerDetectsTrackers(t *testing.T) {
+	report := NewPrivacyAnalyzer(defaultTrackerSignatures(), defaultConsentSignatures()).Analyze([]byte(trackingTestHTML))
+
+	if report.TrackerCount != 3 {
+		t.Errorf("expected 3 trackers, got %d: %+v", report.TrackerCount, report.Trackers)
+	}
+
+	analytics := trackerNames(report.Trackers[TrackerPurposeAnalytics])
+	if len(analytics) != 2 || analytics[0] != "Google Analytics" || analytics[1] != "Google Tag Manager" {
+		t.Errorf("expected Google Analytics and Google Tag Manager, got %v", analytics)
+	}
+
+	advertising := report.Trackers[TrackerPurposeAdvertising]
+	if len(advertising) != 1 || advertising[0].Name != "Meta Pixel" || advertising[0].Host != "www.facebook.com" {
+		t.Errorf("expected the Meta Pixel from www.facebook.com, got %+v", advertising)
+	}
+
+	if social := report.Trackers[TrackerPurposeSocial]; len(social) != 0 {
+		t.Errorf("expected no social trackers, got %+v", social)
+	}
+}
+
+func TestPrivacyAnalyzerIgnoresFirstPartyAndDuplicates(t *testing.T) {
+	body := `<script src="/js/analytics.js"></script>
+<script src="https://example.com/google-analytics.com.js"></script>
+<script src="https://ssl.google-analytics.com/ga.js"></script>
+<script src="https://www.google-analytics.com/analytics.js"></script>
+<script src="https://notgoogle-analytics.com/x.js"></script>`
+
+	report := NewPrivacyAnalyzer(defaultTrackerSignatures(), defaultConsentSignatures()).Analyze([]byte(body))
+
+	if report.TrackerCount != 1 {
+		t.Errorf("expected a single Google Analytics tracker, got %d: %+v", report.TrackerCount, report.Trackers)
+	}
+}
+
+func TestPrivacyAnalyzerParsesAwkwardMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string // the single tracker found, or "" for none
+	}{
+		{"attributes across lines", "<script\n  async\n  src=\"https://static.hotjar.com/c/hotjar-1.js\"\n></script>", "Hotjar"},
+		{"angle bracket before src", `<img alt="a > b" src="https://www.facebook.com/tr?id=1">`, "Meta Pixel"},
+		{"uppercase tag and attribute", `<SCRIPT SRC="https://www.clarity.ms/tag/abc"></SCRIPT>`, "Microsoft Clarity"},
+		{"entity-encoded url", `<img src="https://www.facebook.com/tr?id=1&amp;ev=PageView">`, "Meta Pixel"},
+		{"lazy-load data-src", `<img data-src="https://www.facebook.com/tr?id=1" src="/placeholder.gif">`, ""},
+		{"commented-out script", `<!-- <script src="https://static.hotjar.com/c/hotjar-1.js"></script> -->`, ""},
+		{"src inside inline script", `<script>var s = '<img src="https://www.facebook.com/tr?id=1">';</script>`, ""},
+	}
+
+	analyzer := NewPrivacyAnalyzer(defaultTrackerSignatures(), defaultConsentSignatures())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzer.Analyze([]byte(tt.body))
+			var names []string
+			for _, trackers := range report.Trackers {
+				names = append(names, trackerNames(trackers)...)
+			}
+			if tt.expected == "" && len(names) != 0 {
+				t.Errorf("expected no trackers, got %v", names)
+			}
+			if tt.expected != "" && (len(names) != 1 || names[0] != tt.expected) {
+				t.Errorf("expected only %s, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerReportsPrivacy(t *testing.T) {
+	rr := postAnalyze(t, map[string]string{"html": trackingTestHTML})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if response.Privacy == nil {
+		t.Fatal("expected a privacy report in the response")
+	}
+	if response.Privacy.TrackerCount != 3 {
+		t.Errorf("expected 3 trackers, got %d", response.Privacy.TrackerCount)
+	}
+}
+
+func TestLoadTrackerSignatures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trackers.json")
+	custom := `[
+		{"host": "stats.example.net", "name": "Example Stats", "purpose": "analytics"},
+		{"host": "connect.facebook.net", "name": "Facebook Connect", "purpose": "social"}
+	]`
+	if err := os.WriteFile(path, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	signatures, err := loadTrackerSignatures(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	analyzer := NewPrivacyAnalyzer(signatures, defaultConsentSignatures())
+	report := analyzer.Analyze([]byte(`<script src="https://cdn.stats.example.net/s.js"></script>
+<script src="https://connect.facebook.net/en_US/sdk.js"></script>
+<script src="https://www.google-analytics.com/analytics.js"></script>`))
+
+	analytics := trackerNames(report.Trackers[TrackerPurposeAnalytics])
+	if len(analytics) != 2 || analytics[0] != "Example Stats" || analytics[1] != "Google Analytics" {
+		t.Errorf("expected custom and built-in analytics trackers, got %v", analytics)
+	}
+	social := trackerNames(report.Trackers[TrackerPurposeSocial])
+	if len(social) != 1 || social[0] != "Facebook Connect" {
+		t.Errorf("expected the custom entry to replace the built-in one, got %v", social)
+	}
+}
+
+func TestLoadTrackerSignaturesInvalid(t *testing.T) {
+	tests := map[string]string{
+		"malformed JSON":  `{`,
+		"missing host":    `[{"name": "X", "purpose": "analytics"}]`,
+		"unknown purpose": `[{"host": "x.example", "name": "X", "purpose": "fingerprinting"}]`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "trackers.json")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadTrackerSignatures(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestPrivacyAnalyzerDetectsConsentPlatform(t *testing.T) {
+	// Standard OneTrust embed snippet
+	body := `<html><head>
+<script src="https://cdn.cookielaw.org/scripttemplates/otSDKStub.js" type="text/javascript" charset="UTF-8" data-domain-script="0190a1b2-0000-0000-0000-000000000000"></script>
+<script type="text/javascript">function OptanonWrapper() { }</script>
+</head><body></body></html>`
+
+	consent := NewPrivacyAnalyzer(defaultTrackerSignatures(), defaultConsentSignatures()).Analyze([]byte(body)).Consent
+
+	if !consent.Detected {
+		t.Error("expected a consent mechanism to be detected")
+	}
+	if len(consent.Platforms) != 1 || consent.Platforms[0] != "OneTrust" {
+		t.Errorf("expected OneTrust, got %v", consent.Platforms)
+	}
+	if consent.BannerMarkup {
+		t.Error("the CMP loader alone should not count as banner markup")
+	}
+}
+
+func TestPrivacyAnalyzerDetectsConsentBannerMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected bool
+	}{
+		{"banner id", `<div id="cookie-banner"><button>Accept</button></div>`, true},
+		{"banner among classes", `<div class="modal gdpr_notice hidden">We value your privacy</div>`, true},
+		{"unquoted class", `<section class=consent-popup>`, true},
+		{"unrelated cookie text", `<p>Our cookie recipes are the best.</p>`, false},
+		{"unrelated class", `<div class="cookiejar">`, false},
+		{"banner after angle bracket in attribute", `<div title="1 > 0" class="cookie-notice">`, true},
+		{"banner class in comment", `<!-- <div class="cookie-banner"></div> -->`, false},
+		{"banner name in text", `<p>Style your cookie-banner with CSS.</p>`, false},
+	}
+
+	analyzer := NewPrivacyAnalyzer(defaultTrackerSignatures(), defaultConsentSignatures())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consent := analyzer.Analyze([]byte(tt.body)).Consent
+			if consent.BannerMarkup != tt.expected || consent.Detected != tt.expected {
+				t.Errorf("expected banner markup %v, got %+v", tt.expected, consent)
+			}
+		})
+	}
+}
+
+func TestPrivacyAnalyzerNoConsent(t *testing.T) {
+	consent := NewPrivacyAnalyzer(defaultTrackerSignatures(), defaultConsentSignatures()).Analyze([]byte(trackingTestHTML)).Consent
+
+	if consent.Detected || len(consent.Platforms) != 0 {
+		t.Errorf("expected no consent mechanism, got %+v", consent)
+	}
+}
+
+func TestLoadConsentSignatures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "consent.json")
+	custom := `[{"name": "In-House CMP", "patterns": ["consent.example.com"]}]`
+	if err := os.WriteFile(path, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	signatures, err := loadConsentSignatures(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	analyzer := NewPrivacyAnalyzer(defaultTrackerSignatures(), signatures)
+	consent := analyzer.Analyze([]byte(`<script src="https://consent.example.com/cmp.js"></script>
+<script src="https://consent.cookiebot.com/uc.js"></script>`)).Consent
+
+	if len(consent.Platforms) != 2 || consent.Platforms[0] != "Cookiebot" || consent.Platforms[1] != "In-House CMP" {
+		t.Errorf("expected custom and built-in platforms, got %v", consent.Platforms)
+	}
+
+	for name, content := range map[string]string{
+		"missing patterns": `[{"name": "X"}]`,
+		"empty pattern":    `[{"name": "X", "patterns": [" "]}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "consent.json")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadConsentSignatures(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
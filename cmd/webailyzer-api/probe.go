@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sensitiveProbeConcurrency bounds the HEAD requests in flight for a single analysis
+const sensitiveProbeConcurrency = 4
+
+// defaultSensitivePaths returns the files probed unless SENSITIVE_PATHS overrides them.
+// Each is commonly left in a web root by mistake and leaks source or credentials.
+func defaultSensitivePaths() []string {
+	return []string{"/.git/HEAD", "/.env", "/backup.zip", "/wp-config.php.bak"}
+}
+
+// Warnings added when a probe is cut off before it finishes
+const (
+	sensitivePathsTimeoutWarning = "Sensitive path probing did not finish within its time limit; exposed_paths may be incomplete"
+	corsProbeTimeoutWarning      = "The CORS probe did not finish within its time limit, so origin reflection was not checked"
+)
+
+// ProbeConfig bounds the auxiliary requests an analysis sends besides the page fetch,
+// so that a slow or flaky site cannot spend the whole analysis budget on them
+type ProbeConfig struct {
+	// Timeout bounds a whole probe, retries included
+	Timeout time.Duration
+	// MaxAttempts is the total number of tries for a request that fails transiently
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubling after each failure
+	Backoff time.Duration
+}
+
+// defaultProbeConfig returns the probe limits used unless PROBE_* settings override them
+func defaultProbeConfig() ProbeConfig {
+	return ProbeConfig{Timeout: 5 * time.Second, MaxAttempts: 2, Backoff: 250 * time.Millisecond}
+}
+
+// probeClient returns the client for probe requests. It shares the fetch transport but
+// never follows redirects: probes judge the response of the URL they asked for.
+func probeClient() *http.Client {
+	client := *createHTTPClient()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client
+}
+
+// doProbe sends a probe request, retrying network errors and 5xx responses as
+// fetchWithRetry does, within cfg.MaxAttempts. The body of the returned response is
+// already closed; probes only look at the status and headers.
+func doProbe(ctx context.Context, client *http.Client, req *http.Request, cfg ProbeConfig) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req.Clone(ctx))
+		retryable := false
+		if err != nil {
+			retryable = isRetryableFetchError(ctx, err)
+		} else {
+			resp.Body.Close()
+			retryable = resp.StatusCode >= 500
+		}
+		if !retryable || attempt >= cfg.MaxAttempts {
+			return resp, err
+		}
+
+		timer := time.NewTimer(cfg.Backoff << (attempt - 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// probeSensitivePaths sends a HEAD request for each path on the host of pageURL and
+// returns the sorted paths that answered 200 OK. Redirects are not followed, since
+// sites often redirect unknown paths to a page that would answer 200, and nothing is
+// reported for sites that answer 200 for a path that cannot exist. Probing is bounded
+// by cfg.Timeout; when it is cut off, the paths found so far are returned with the
+// context error.
+func probeSensitivePaths(ctx context.Context, pageURL string, paths []string, cfg ProbeConfig) ([]string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil || base.Host == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	client := probeClient()
+	reachable := func(path string) bool {
+		target := url.URL{Scheme: base.Scheme, Host: base.Host, Path: path}
+		req, err := http.NewRequestWithContext(ctx, "HEAD", target.String(), nil)
+		if err != nil {
+			return false
+		}
+		req.Header.Set("User-Agent", defaultUserAgent)
+		resp, err := doProbe(ctx, client, req, cfg)
+		if err != nil {
+			return false
+		}
+		return resp.StatusCode == http.StatusOK
+	}
+
+	// A catch-all site would make every path look exposed
+	control, err := newAnalysisID()
+	if err != nil {
+		return nil, nil
+	}
+	if reachable("/" + control) {
+		return nil, nil
+	}
+
+	var (
+		mu       sync.Mutex
+		exposed  []string
+		finished int
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, sensitiveProbeConcurrency)
+
+	for _, path := range paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			found := reachable(path)
+			mu.Lock()
+			defer mu.Unlock()
+			// A request cut off by the deadline has no answer either way
+			if found || ctx.Err() == nil {
+				finished++
+			}
+			if found {
+				exposed = append(exposed, path)
+			}
+		}(path)
+	}
+	wg.Wait()
+
+	sort.Strings(exposed)
+	if finished < len(paths) {
+		return exposed, ctx.Err()
+	}
+	return exposed, nil
+}
+
+// exposedPathFindings reports each reachable sensitive path as a critical finding
+func exposedPathFindings(paths []string) []SecurityFinding {
+	findings := make([]SecurityFinding, 0, len(paths))
+	for _, path := range paths {
+		findings = append(findings, SecurityFinding{
+			Type:     "information_disclosure",
+			Severity: "critical",
+			Subject:  path,
+			Message:  fmt.Sprintf("%s is publicly reachable; remove it from the web root or deny access to it", path),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newExposedSite serves a page with /.env left in the web root and redirects /backup.zip
+func newExposedSite(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var probes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			probes.Add(1)
+		}
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Exposed</title></head><body></body></html>`))
+		case "/.env":
+			w.Write([]byte("DB_PASSWORD=secret"))
+		case "/backup.zip":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &probes
+}
+
+func TestProbeSensitivePaths(t *testing.T) {
+	server, _ := newExposedSite(t)
+
+	exposed, err := probeSensitivePaths(context.Background(), server.URL+"/some/page", defaultSensitivePaths(), defaultProbeConfig())
+	if err != nil || !reflect.DeepEqual(exposed, []string{"/.env"}) {
+		t.Errorf("expected only /.env to be reachable, got %v (err %v)", exposed, err)
+	}
+}
+
+func TestProbeSensitivePathsIgnoresCatchAllSites(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>Single-page app</body></html>`))
+	}))
+	defer server.Close()
+
+	if exposed, _ := probeSensitivePaths(context.Background(), server.URL, defaultSensitivePaths(), defaultProbeConfig()); len(exposed) != 0 {
+		t.Errorf("expected nothing reported for a site answering 200 everywhere, got %v", exposed)
+	}
+}
+
+func TestProbeSensitivePathsRespectsDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := probeSensitivePaths(ctx, server.URL, defaultSensitivePaths(), defaultProbeConfig())
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected probing to stop at the deadline, took %v", elapsed)
+	}
+	if err == nil {
+		t.Error("expected an error for probing cut off by the deadline")
+	}
+}
+
+func TestProbeSensitivePathsStopsAtProbeTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	cfg := defaultProbeConfig()
+	cfg.Timeout = 100 * time.Millisecond
+
+	// The analysis budget is far larger; the probe's own limit must cut it off
+	start := time.Now()
+	_, err := probeSensitivePaths(context.Background(), server.URL, defaultSensitivePaths(), cfg)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected probing to stop at PROBE_TIMEOUT, took %v", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
+
+func TestProbeSensitivePathsRetriesFlakyTarget(t *testing.T) {
+	var envRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.env" {
+			http.NotFound(w, r)
+			return
+		}
+		// The first request for /.env fails, as an overloaded backend would
+		if envRequests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := defaultProbeConfig()
+	cfg.Backoff = time.Millisecond
+
+	exposed, err := probeSensitivePaths(context.Background(), server.URL, []string{"/.env"}, cfg)
+	if err != nil || !reflect.DeepEqual(exposed, []string{"/.env"}) {
+		t.Errorf("expected the retry to find /.env, got %v (err %v)", exposed, err)
+	}
+	if n := envRequests.Load(); n != 2 {
+		t.Errorf("expected 2 requests for /.env, got %d", n)
+	}
+
+	envRequests.Store(0)
+	cfg.MaxAttempts = 1
+	if exposed, _ := probeSensitivePaths(context.Background(), server.URL, []string{"/.env"}, cfg); len(exposed) != 0 {
+		t.Errorf("expected no retry with a single attempt, got %v", exposed)
+	}
+}
+
+func TestAnalyzeHandlerWarnsWhenProbesTimeOut(t *testing.T) {
+	original := appConfig
+	appConfig.Probe.Timeout = 100 * time.Millisecond
+	defer func() { appConfig = original }()
+
+	// The page itself is fast; every probe request stalls
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" || r.Header.Get("Origin") != "" {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Slow probes</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	rr := runPipeline(t, map[string]interface{}{"url": server.URL, "probe_sensitive_paths": true, "probe_cors": true})
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the probes to give up after PROBE_TIMEOUT, took %v", elapsed)
+	}
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, warning := range []string{sensitivePathsTimeoutWarning, corsProbeTimeoutWarning} {
+		if !containsString(response.Warnings, warning) {
+			t.Errorf("expected warning %q, got %v", warning, response.Warnings)
+		}
+	}
+}
+
+func TestAnalyzeHandlerProbesSensitivePathsOnlyWhenEnabled(t *testing.T) {
+	server, probes := newExposedSite(t)
+
+	rr := runPipeline(t, map[string]interface{}{"url": server.URL})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if n := probes.Load(); n != 0 {
+		t.Fatalf("expected no probes unless enabled, got %d", n)
+	}
+
+	rr = runPipeline(t, map[string]interface{}{"url": server.URL, "probe_sensitive_paths": true})
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !reflect.DeepEqual(response.ExposedPaths, []string{"/.env"}) {
+		t.Errorf("expected exposed_paths [/.env], got %v", response.ExposedPaths)
+	}
+	found := false
+	for _, f := range response.SecurityFindings {
+		if f.Type == "information_disclosure" && f.Severity == "critical" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a critical information_disclosure finding, got %v", response.SecurityFindings)
+	}
+	if _, ok := response.Timings["sensitive_paths"]; !ok {
+		t.Errorf("expected a sensitive_paths timing, got %v", response.Timings)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"container/list"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// probeResults caches probe results across analyses; nil disables caching. It is
+// replaced by main() from PROBE_CACHE_SIZE and PROBE_CACHE_TTL.
+var probeResults *probeCache
+
+// probeCache remembers probe results per site so that analyzing the same site again
+// does not probe it again. Entries expire after ttl, and the least recently used entry
+// is dropped once size entries are held. Analyses of one site that run at the same
+// time may each probe it; the last result stored wins.
+type probeCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+	now     func() time.Time
+}
+
+// probeCacheEntry is a cached result and when it stops being served
+type probeCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// newProbeCache creates an empty cache holding at most size results for ttl each
+func newProbeCache(size int, ttl time.Duration) *probeCache {
+	return &probeCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// get returns the unexpired result stored under key
+func (c *probeCache) get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*probeCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// put stores value under key, evicting the least recently used result if the cache is full
+func (c *probeCache) put(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*probeCacheEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&probeCacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*probeCacheEntry).key)
+	}
+}
+
+// len returns the number of results held, expired ones included until they are next read
+func (c *probeCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// cached returns the result stored under key, or runs probe and stores its result.
+// Results of probes that were cut off are incomplete and never stored.
+func (c *probeCache) cached(key string, probe func() (interface{}, error)) (interface{}, error) {
+	if value, ok := c.get(key); ok {
+		return value, nil
+	}
+	value, err := probe()
+	if err == nil {
+		c.put(key, value)
+	}
+	return value, err
+}
+
+// probeCacheKey keys a probe result by the probe and the site it concerns. Probes of
+// a whole site pass the page URL through siteOrigin so every page of the site shares
+// the result.
+func probeCacheKey(probe, target string) string {
+	return probe + " " + target
+}
+
+// siteOrigin returns the scheme and host of pageURL, such as https://example.com
+func siteOrigin(pageURL string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
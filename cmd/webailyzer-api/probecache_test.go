@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProbeCacheEviction(t *testing.T) {
+	cache := newProbeCache(2, time.Hour)
+
+	cache.put("a", 1)
+	cache.put("b", 2)
+	// Reading a makes b the least recently used
+	if v, ok := cache.get("a"); !ok || v != 1 {
+		t.Fatalf("expected a cached, got %v, %v", v, ok)
+	}
+	cache.put("c", 3)
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("expected the least recently used result to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("expected %s to stay cached", key)
+		}
+	}
+	if n := cache.len(); n != 2 {
+		t.Errorf("expected 2 cached results, got %d", n)
+	}
+}
+
+func TestProbeCacheExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newProbeCache(10, 10*time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.put("site", "result")
+	now = now.Add(9 * time.Minute)
+	if _, ok := cache.get("site"); !ok {
+		t.Fatal("expected the result to be served before the TTL")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := cache.get("site"); ok {
+		t.Error("expected the result to expire once the TTL passes")
+	}
+	if n := cache.len(); n != 0 {
+		t.Errorf("expected the expired result to be dropped, got %d", n)
+	}
+}
+
+func TestProbeCacheSkipsCutOffProbes(t *testing.T) {
+	cache := newProbeCache(10, time.Hour)
+
+	calls := 0
+	probe := func() (interface{}, error) {
+		calls++
+		return []string{"/.env"}, context.DeadlineExceeded
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cache.cached("site", probe); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the probe error, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected an incomplete result to be probed again, probed %d times", calls)
+	}
+
+	// A nil cache always probes
+	var disabled *probeCache
+	if v, err := disabled.cached("site", func() (interface{}, error) { return 1, nil }); v != 1 || err != nil {
+		t.Errorf("expected the probe result from a disabled cache, got %v, %v", v, err)
+	}
+}
+
+func TestAnalyzeHandlerReusesCachedProbes(t *testing.T) {
+	original := probeResults
+	probeResults = newProbeCache(10, time.Hour)
+	defer func() { probeResults = original }()
+
+	server, heads := newExposedSite(t)
+	request := map[string]interface{}{
+		"url":                   server.URL,
+		"probe_sensitive_paths": true,
+		"probe_cors":            true,
+		"probe_https_redirect":  true,
+	}
+
+	var responses [2]AnalyzeResponse
+	probesSent := make([]int32, 2)
+	for i := range responses {
+		rr := runPipeline(t, request)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &responses[i]); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		probesSent[i] = heads.Load()
+	}
+
+	if probesSent[0] == 0 {
+		t.Fatal("expected the first analysis to probe the site")
+	}
+	if probesSent[1] != probesSent[0] {
+		t.Errorf("expected the second analysis to reuse the probe results, sent %d more probes", probesSent[1]-probesSent[0])
+	}
+	if !reflect.DeepEqual(responses[1].ExposedPaths, []string{"/.env"}) {
+		t.Errorf("expected cached exposed_paths [/.env], got %v", responses[1].ExposedPaths)
+	}
+	if !reflect.DeepEqual(responses[0].SecurityFindings, responses[1].SecurityFindings) {
+		t.Errorf("expected the same findings from cached probes, got %+v and %+v", responses[0].SecurityFindings, responses[1].SecurityFindings)
+	}
+	if responses[1].HTTPSRedirect == nil {
+		t.Error("expected the cached https_redirect check")
+	}
+}
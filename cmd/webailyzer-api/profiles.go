@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// defaultUserAgent identifies our service on outbound fetches
+const defaultUserAgent = "WebAIlyzer-Lite-API/1.0"
+
+// ScanProfile is a named bundle of analysis defaults selectable via AnalyzeRequest.Profile
+type ScanProfile struct {
+	UserAgent      string `json:"user_agent,omitempty"`
+	TimeoutMs      int    `json:"timeout_ms,omitempty"`
+	IncludeHeaders bool   `json:"include_headers,omitempty"`
+	// ProbeSensitivePaths sends extra HEAD requests for exposed files; off unless enabled
+	ProbeSensitivePaths bool `json:"probe_sensitive_paths,omitempty"`
+	// ProbeCORS sends an extra request with an untrusted Origin; off unless enabled
+	ProbeCORS bool `json:"probe_cors,omitempty"`
+	// ProbeHTTPSRedirect requests the plain HTTP site; off unless enabled
+	ProbeHTTPSRedirect bool `json:"probe_https_redirect,omitempty"`
+	// Analyzers names the analyzers run by default; nil runs them all and an empty
+	// list runs technology detection alone
+	Analyzers []string `json:"analyzers,omitempty"`
+}
+
+// ScanOptions are the effective settings for a single analysis
+type ScanOptions struct {
+	Profile        string
+	UserAgent      string
+	Timeout        time.Duration
+	IncludeHeaders bool
+	// ProbeSensitivePaths enables probing for exposed files such as /.env
+	ProbeSensitivePaths bool
+	// ProbeCORS enables checking whether the site reflects an untrusted Origin
+	ProbeCORS bool
+	// ProbeHTTPSRedirect enables checking that plain HTTP redirects to HTTPS
+	ProbeHTTPSRedirect bool
+	// Analyzers names the registered analyzers to run; nil runs them all
+	Analyzers []string
+}
+
+// scanProfiles holds the available profiles, extended at startup from SCAN_PROFILES_FILE
+var scanProfiles = defaultScanProfiles()
+
+// defaultScanProfiles returns the built-in profiles
+func defaultScanProfiles() map[string]ScanProfile {
+	return map[string]ScanProfile{
+		"quick-tech-only": {
+			TimeoutMs: 8000,
+			Analyzers: []string{},
+		},
+		"security-deep": {
+			IncludeHeaders:     true,
+			ProbeCORS:          true,
+			ProbeHTTPSRedirect: true,
+		},
+	}
+}
+
+// loadScanProfiles reads profiles from a JSON file keyed by profile name and merges
+// them over the built-in profiles
+func loadScanProfiles(path string) (map[string]ScanProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scan profiles: %w", err)
+	}
+
+	var custom map[string]ScanProfile
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parsing scan profiles: %w", err)
+	}
+
+	profiles := defaultScanProfiles()
+	for name, profile := range custom {
+		if name == "" {
+			return nil, fmt.Errorf("scan profile names must not be empty")
+		}
+		if profile.TimeoutMs < 0 {
+			return nil, fmt.Errorf("scan profile %q has a negative timeout", name)
+		}
+		if _, err := analyzers.Select(profile.Analyzers); err != nil {
+			return nil, fmt.Errorf("scan profile %q: %w", name, err)
+		}
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+// resolveScanOptions applies the selected profile and then any explicit request fields,
+// which take precedence over profile values
+func resolveScanOptions(req AnalyzeRequest) (ScanOptions, error) {
+	return resolveScanOptionsWithin(req, appConfig.AnalyzeTimeout)
+}
+
+// resolveScanOptionsWithin resolves the scan options with maxTimeout as both the
+// default and the ceiling for the analysis budget
+func resolveScanOptionsWithin(req AnalyzeRequest, maxTimeout time.Duration) (ScanOptions, error) {
+	opts := ScanOptions{
+		Profile:   req.Profile,
+		UserAgent: defaultUserAgent,
+		Timeout:   maxTimeout,
+	}
+
+	if req.Profile != "" {
+		profile, ok := scanProfiles[req.Profile]
+		if !ok {
+			return opts, fmt.Errorf("unknown scan profile %q", req.Profile)
+		}
+		if profile.UserAgent != "" {
+			opts.UserAgent = profile.UserAgent
+		}
+		if profile.TimeoutMs > 0 {
+			opts.Timeout = time.Duration(profile.TimeoutMs) * time.Millisecond
+		}
+		opts.IncludeHeaders = profile.IncludeHeaders
+		opts.ProbeSensitivePaths = profile.ProbeSensitivePaths
+		opts.ProbeCORS = profile.ProbeCORS
+		opts.ProbeHTTPSRedirect = profile.ProbeHTTPSRedirect
+		opts.Analyzers = profile.Analyzers
+	}
+
+	if req.UserAgent != "" {
+		opts.UserAgent = req.UserAgent
+	}
+	if req.TimeoutMs < 0 {
+		return opts, fmt.Errorf("timeout_ms must not be negative")
+	}
+	if req.TimeoutMs > 0 {
+		opts.Timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+	if req.IncludeHeaders != nil {
+		opts.IncludeHeaders = *req.IncludeHeaders
+	}
+	if req.ProbeSensitivePaths != nil {
+		opts.ProbeSensitivePaths = *req.ProbeSensitivePaths
+	}
+	if req.ProbeCORS != nil {
+		opts.ProbeCORS = *req.ProbeCORS
+	}
+	if req.ProbeHTTPSRedirect != nil {
+		opts.ProbeHTTPSRedirect = *req.ProbeHTTPSRedirect
+	}
+	if req.Analyzers != nil {
+		if _, err := analyzers.Select(req.Analyzers); err != nil {
+			return opts, err
+		}
+		opts.Analyzers = req.Analyzers
+	}
+	if err := checkAnalyzerLimit(opts, appConfig.MaxAnalyzers); err != nil {
+		return opts, err
+	}
+
+	// Profiles and requests may shorten the analysis budget but never extend it
+	if opts.Timeout > maxTimeout {
+		opts.Timeout = maxTimeout
+	}
+
+	return opts, nil
+}
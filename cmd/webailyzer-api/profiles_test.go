@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withScanProfiles swaps in the given profiles for the duration of a test
+func withScanProfiles(t *testing.T, profiles map[string]ScanProfile) {
+	original := scanProfiles
+	scanProfiles = profiles
+	t.Cleanup(func() { scanProfiles = original })
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestResolveScanOptionsDefaults(t *testing.T) {
+	opts, err := resolveScanOptions(AnalyzeRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts.UserAgent != defaultUserAgent {
+		t.Errorf("expected default user agent, got %s", opts.UserAgent)
+	}
+	if opts.Timeout != appConfig.AnalyzeTimeout {
+		t.Errorf("expected default timeout %v, got %v", appConfig.AnalyzeTimeout, opts.Timeout)
+	}
+	if opts.IncludeHeaders {
+		t.Error("headers should not be included by default")
+	}
+}
+
+func TestResolveScanOptionsProfile(t *testing.T) {
+	withScanProfiles(t, map[string]ScanProfile{
+		"audit": {UserAgent: "AuditBot/2.0", TimeoutMs: 5000, IncludeHeaders: true},
+	})
+
+	opts, err := resolveScanOptions(AnalyzeRequest{URL: "https://example.com", Profile: "audit"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts.Profile != "audit" {
+		t.Errorf("expected profile audit, got %s", opts.Profile)
+	}
+	if opts.UserAgent != "AuditBot/2.0" {
+		t.Errorf("expected profile user agent, got %s", opts.UserAgent)
+	}
+	if opts.Timeout != 5*time.Second {
+		t.Errorf("expected profile timeout 5s, got %v", opts.Timeout)
+	}
+	if !opts.IncludeHeaders {
+		t.Error("profile should enable header inclusion")
+	}
+}
+
+func TestResolveScanOptionsRequestOverridesProfile(t *testing.T) {
+	withScanProfiles(t, map[string]ScanProfile{
+		"audit": {UserAgent: "AuditBot/2.0", TimeoutMs: 5000, IncludeHeaders: true},
+	})
+
+	opts, err := resolveScanOptions(AnalyzeRequest{
+		URL:            "https://example.com",
+		Profile:        "audit",
+		UserAgent:      "CustomAgent/1.0",
+		TimeoutMs:      2000,
+		IncludeHeaders: boolPtr(false),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts.UserAgent != "CustomAgent/1.0" {
+		t.Errorf("explicit user agent should win, got %s", opts.UserAgent)
+	}
+	if opts.Timeout != 2*time.Second {
+		t.Errorf("explicit timeout should win, got %v", opts.Timeout)
+	}
+	if opts.IncludeHeaders {
+		t.Error("explicit include_headers=false should override the profile")
+	}
+}
+
+func TestResolveScanOptionsValidation(t *testing.T) {
+	if _, err := resolveScanOptions(AnalyzeRequest{URL: "https://example.com", Profile: "missing"}); err == nil {
+		t.Error("unknown profile should return an error")
+	}
+
+	if _, err := resolveScanOptions(AnalyzeRequest{URL: "https://example.com", TimeoutMs: -1}); err == nil {
+		t.Error("negative timeout should return an error")
+	}
+
+	// Timeouts above the server budget are capped
+	opts, err := resolveScanOptions(AnalyzeRequest{URL: "https://example.com", TimeoutMs: 10 * 60 * 1000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Timeout != appConfig.AnalyzeTimeout {
+		t.Errorf("timeout should be capped at %v, got %v", appConfig.AnalyzeTimeout, opts.Timeout)
+	}
+}
+
+func TestLoadScanProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	content := `{"crawler": {"user_agent": "Crawler/1.0", "timeout_ms": 3000}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := loadScanProfiles(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if profiles["crawler"].UserAgent != "Crawler/1.0" || profiles["crawler"].TimeoutMs != 3000 {
+		t.Errorf("custom profile not loaded: %+v", profiles["crawler"])
+	}
+	if _, ok := profiles["quick-tech-only"]; !ok {
+		t.Error("built-in profiles should be kept")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"broken": {"timeout_ms": -5}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadScanProfiles(path); err == nil {
+		t.Error("negative profile timeout should be rejected")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"broken": {"analyzers": ["security"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadScanProfiles(path); err == nil {
+		t.Error("unknown profile analyzers should be rejected")
+	}
+}
+
+func TestResolveScanOptionsAnalyzerPrecedence(t *testing.T) {
+	withScanProfiles(t, map[string]ScanProfile{
+		"cookies-only": {Analyzers: []string{"cookies"}},
+		"tech-only":    {Analyzers: []string{}},
+		"everything":   {},
+	})
+
+	tests := []struct {
+		name     string
+		profile  string
+		request  []string
+		expected []string // nil runs every analyzer
+	}{
+		{"no profile", "", nil, nil},
+		{"profile without a default", "everything", nil, nil},
+		{"profile default", "cookies-only", nil, []string{"cookies"}},
+		{"empty profile default", "tech-only", nil, []string{}},
+		{"request overrides profile", "cookies-only", []string{"cors", "privacy"}, []string{"cors", "privacy"}},
+		{"request selects none", "cookies-only", []string{}, []string{}},
+		{"request overrides empty profile", "tech-only", []string{"cors"}, []string{"cors"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := resolveScanOptions(AnalyzeRequest{URL: "https://example.com", Profile: tt.profile, Analyzers: tt.request})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (opts.Analyzers == nil) != (tt.expected == nil) || strings.Join(opts.Analyzers, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected analyzers %#v, got %#v", tt.expected, opts.Analyzers)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerQuickTechOnlySkipsAnalyzers(t *testing.T) {
+	decode := func(rr *httptest.ResponseRecorder) AnalyzeResponse {
+		t.Helper()
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response AnalyzeResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	quick := decode(runPipeline(t, map[string]interface{}{"html": inlineTestHTML, "profile": "quick-tech-only"}))
+	if quick.Privacy != nil {
+		t.Errorf("expected quick-tech-only to skip the privacy analyzer, got %+v", quick.Privacy)
+	}
+	for _, name := range analyzers.Names() {
+		if _, ok := quick.Timings[name]; ok {
+			t.Errorf("expected quick-tech-only to run no analyzers, got timing for %s", name)
+		}
+	}
+
+	selected := decode(runPipeline(t, map[string]interface{}{"html": inlineTestHTML, "profile": "quick-tech-only", "analyzers": []string{"privacy"}}))
+	if selected.Privacy == nil {
+		t.Error("expected the request's analyzers to override the profile")
+	}
+}
+
+func TestAnalyzeHandlerAppliesProfile(t *testing.T) {
+	withScanProfiles(t, map[string]ScanProfile{
+		"audit": {UserAgent: "AuditBot/2.0", IncludeHeaders: true},
+	})
+
+	var receivedUA string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedUA = r.UserAgent()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Profiled</body></html>`))
+	}))
+	defer server.Close()
+
+	requestBody := fmt.Sprintf(`{"url":"%s","profile":"audit"}`, server.URL)
+	req, err := http.NewRequest("POST", "/v1/analyze", strings.NewReader(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if receivedUA != "AuditBot/2.0" {
+		t.Errorf("expected profile user agent to be sent, got %s", receivedUA)
+	}
+	if response.Profile != "audit" {
+		t.Errorf("expected profile to be echoed, got %s", response.Profile)
+	}
+	if response.Headers == nil {
+		t.Error("profile should include headers in the response")
+	}
+}
+
+func TestAnalyzeHandlerUnknownProfile(t *testing.T) {
+	req, err := http.NewRequest("POST", "/v1/analyze", strings.NewReader(`{"url":"https://example.com","profile":"nope"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rr.Code)
+	}
+
+	var response ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Type != ErrorTypeValidation {
+		t.Errorf("expected validation error, got %s", response.Type)
+	}
+}
+
+func TestAnalyzeHandlerReportsDeadline(t *testing.T) {
+	original := appConfig
+	appConfig.AnalyzeTimeout = 12 * time.Second
+	defer func() { appConfig = original }()
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want int64
+	}{
+		{"configured timeout", map[string]interface{}{"html": inlineTestHTML}, 12000},
+		{"shortened by request", map[string]interface{}{"html": inlineTestHTML, "timeout_ms": 2500}, 2500},
+		{"capped at configuration", map[string]interface{}{"html": inlineTestHTML, "timeout_ms": 60000}, 12000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postAnalyze(t, tt.body)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			var response AnalyzeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if response.DeadlineMs != tt.want {
+				t.Errorf("expected deadline_ms %d, got %d", tt.want, response.DeadlineMs)
+			}
+		})
+	}
+}
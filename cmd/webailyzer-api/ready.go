@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	wappalyzer "github.com/projectdiscovery/wappalyzergo"
+	"github.com/sirupsen/logrus"
+)
+
+// readyDNSTimeout bounds the optional outbound DNS check
+const readyDNSTimeout = 2 * time.Second
+
+// Readiness dependencies; variables so tests can simulate failures
+var (
+	readyEngine = getWappalyzer
+	lookupHost  = net.DefaultResolver.LookupHost
+)
+
+// ReadyResponse represents the readiness probe response
+type ReadyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// readyHandler handles GET /ready requests. Unlike /health it verifies that the
+// detection engine is usable and, when READY_CHECK_HOST is set, that outbound DNS works.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := ""
+	if id := r.Context().Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	response := ReadyResponse{Status: "ready", Checks: map[string]string{}}
+
+	if err := checkEngine(readyEngine); err != nil {
+		response.Status = "not_ready"
+		response.Checks["engine"] = err.Error()
+	} else {
+		response.Checks["engine"] = "ok"
+	}
+
+	if host := appConfig.ReadyCheckHost; host != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), readyDNSTimeout)
+		defer cancel()
+		if _, err := lookupHost(ctx, host); err != nil {
+			response.Status = "not_ready"
+			response.Checks["dns"] = err.Error()
+		} else {
+			response.Checks["dns"] = "ok"
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status != "ready" {
+		status = http.StatusServiceUnavailable
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"checks":     response.Checks,
+		}).Warn("Readiness check failed")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to encode readiness response")
+	}
+}
+
+// checkEngine reports why the shared detection engine is unusable, or nil when it is ready
+func checkEngine(engine func() (*wappalyzer.Wappalyze, error)) error {
+	wc, err := engine()
+	if err != nil {
+		return err
+	}
+	if wc == nil {
+		return errors.New("detection engine is not initialized")
+	}
+	return nil
+}
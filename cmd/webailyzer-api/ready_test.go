@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	wappalyzer "github.com/projectdiscovery/wappalyzergo"
+)
+
+func getReady(t *testing.T) (int, ReadyResponse) {
+	t.Helper()
+
+	req, err := http.NewRequest("GET", "/ready", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	readyHandler(rr, req)
+
+	var response ReadyResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	return rr.Code, response
+}
+
+func TestReadyHandler(t *testing.T) {
+	originalEngine, originalLookup, originalConfig := readyEngine, lookupHost, appConfig
+	defer func() {
+		readyEngine, lookupHost, appConfig = originalEngine, originalLookup, originalConfig
+	}()
+
+	resolves := func(ctx context.Context, host string) ([]string, error) { return []string{"192.0.2.1"}, nil }
+	fails := func(ctx context.Context, host string) ([]string, error) { return nil, errors.New("no such host") }
+
+	tests := []struct {
+		name      string
+		engine    func() (*wappalyzer.Wappalyze, error)
+		checkHost string
+		lookup    func(ctx context.Context, host string) ([]string, error)
+		status    int
+		checks    map[string]string
+	}{
+		{"ready", getWappalyzer, "", resolves, http.StatusOK, map[string]string{"engine": "ok"}},
+		{"ready with dns", getWappalyzer, "example.com", resolves, http.StatusOK, map[string]string{"engine": "ok", "dns": "ok"}},
+		{"nil engine", func() (*wappalyzer.Wappalyze, error) { return nil, nil }, "", resolves, http.StatusServiceUnavailable,
+			map[string]string{"engine": "detection engine is not initialized"}},
+		{"engine error", func() (*wappalyzer.Wappalyze, error) { return nil, errors.New("fingerprints failed to load") }, "", resolves, http.StatusServiceUnavailable,
+			map[string]string{"engine": "fingerprints failed to load"}},
+		{"dns failure", getWappalyzer, "example.com", fails, http.StatusServiceUnavailable, map[string]string{"engine": "ok", "dns": "no such host"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readyEngine, lookupHost = tt.engine, tt.lookup
+			appConfig.ReadyCheckHost = tt.checkHost
+
+			status, response := getReady(t)
+			if status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, status)
+			}
+			wantStatus := "ready"
+			if tt.status != http.StatusOK {
+				wantStatus = "not_ready"
+			}
+			if response.Status != wantStatus {
+				t.Errorf("expected status %q, got %q", wantStatus, response.Status)
+			}
+			if len(response.Checks) != len(tt.checks) {
+				t.Errorf("expected checks %v, got %v", tt.checks, response.Checks)
+			}
+			for name, want := range tt.checks {
+				if response.Checks[name] != want {
+					t.Errorf("expected %s check %q, got %q", name, want, response.Checks[name])
+				}
+			}
+		})
+	}
+}
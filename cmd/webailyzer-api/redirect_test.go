@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeHandlerFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Final</title></head><body>Landed</body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	requestBody, _ := json.Marshal(map[string]string{"url": server.URL + "/start"})
+	req, err := http.NewRequest("POST", "/v1/analyze", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if response.URL != server.URL+"/start" {
+		t.Errorf("URL should echo the requested URL, got %s", response.URL)
+	}
+
+	if response.FinalURL != server.URL+"/final" {
+		t.Errorf("expected final URL %s, got %s", server.URL+"/final", response.FinalURL)
+	}
+
+	if !response.Redirected {
+		t.Error("redirected should be true")
+	}
+
+	expectedChain := []string{server.URL + "/middle", server.URL + "/final"}
+	if len(response.RedirectChain) != len(expectedChain) {
+		t.Fatalf("expected redirect chain %v, got %v", expectedChain, response.RedirectChain)
+	}
+	for i, hop := range expectedChain {
+		if response.RedirectChain[i] != hop {
+			t.Errorf("redirect hop %d: expected %s, got %s", i, hop, response.RedirectChain[i])
+		}
+	}
+}
+
+func TestAnalyzeHandlerWithoutRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>No redirect</body></html>`))
+	}))
+	defer server.Close()
+
+	requestBody, _ := json.Marshal(map[string]string{"url": server.URL})
+	req, err := http.NewRequest("POST", "/v1/analyze", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if response.Redirected {
+		t.Error("redirected should be false when no redirect occurred")
+	}
+
+	if len(response.RedirectChain) != 0 {
+		t.Errorf("redirect chain should be empty, got %v", response.RedirectChain)
+	}
+
+	if response.FinalURL != server.URL {
+		t.Errorf("final URL should match the requested URL, got %s", response.FinalURL)
+	}
+}
+
+func TestAnalyzeHandlerDetectsHTTPSDowngrade(t *testing.T) {
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Insecure landing page</body></html>`))
+	}))
+	defer plainServer.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plainServer.URL+"/landing", http.StatusFound)
+	}))
+	defer tlsServer.Close()
+
+	// Trust the test server certificate for the duration of the test
+	initHTTPClient()
+	defer initHTTPClient()
+	transport := createHTTPClient().Transport.(*http.Transport)
+	transport.TLSClientConfig = tlsServer.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	defer transport.CloseIdleConnections()
+
+	requestBody, _ := json.Marshal(map[string]string{"url": tlsServer.URL})
+	req, err := http.NewRequest("POST", "/v1/analyze", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(response.RedirectHops) != 2 {
+		t.Fatalf("expected 2 redirect hops, got %v", response.RedirectHops)
+	}
+
+	if response.RedirectHops[0].Scheme != "https" || response.RedirectHops[1].Scheme != "http" {
+		t.Errorf("unexpected hop schemes: %v", response.RedirectHops)
+	}
+
+	if len(response.SecurityFindings) != 1 {
+		t.Fatalf("expected 1 security finding, got %v", response.SecurityFindings)
+	}
+
+	if response.SecurityFindings[0].Type != "https_downgrade" {
+		t.Errorf("expected https_downgrade finding, got %s", response.SecurityFindings[0].Type)
+	}
+}
+
+func TestDetectSchemeDowngrades(t *testing.T) {
+	tests := []struct {
+		name     string
+		hops     []RedirectHop
+		expected int
+	}{
+		{
+			name:     "no redirects",
+			hops:     buildRedirectHops("https://example.com", nil),
+			expected: 0,
+		},
+		{
+			name:     "http to https upgrade",
+			hops:     buildRedirectHops("http://example.com", []string{"https://example.com"}),
+			expected: 0,
+		},
+		{
+			name:     "https to http downgrade",
+			hops:     buildRedirectHops("https://example.com", []string{"http://cdn.example.com"}),
+			expected: 1,
+		},
+		{
+			name:     "downgrade later in the chain",
+			hops:     buildRedirectHops("http://example.com", []string{"https://example.com", "http://example.com/login"}),
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := detectSchemeDowngrades(tt.hops)
+			if len(findings) != tt.expected {
+				t.Errorf("expected %d findings, got %d: %v", tt.expected, len(findings), findings)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandlerRedirectLoop(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	requestBody, _ := json.Marshal(map[string]string{"url": server.URL + "/a"})
+	req, err := http.NewRequest("POST", "/v1/analyze", bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	analyzeHandler(rr, req)
+
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if response.Type != ErrorTypeTooManyRedirects {
+		t.Errorf("expected error type %s, got %s", ErrorTypeTooManyRedirects, response.Type)
+	}
+	if !strings.Contains(response.Details, "Stopped after 10 redirects") {
+		t.Errorf("expected redirect count in details, got %q", response.Details)
+	}
+	loop := server.URL + "/a -> " + server.URL + "/b"
+	if !strings.Contains(response.Details, loop) {
+		t.Errorf("expected looping URLs %q in details, got %q", loop, response.Details)
+	}
+}
+
+func TestTooManyRedirectsErrorLoopURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		urls     []string
+		expected []string
+	}{
+		{
+			name:     "two-URL loop",
+			urls:     []string{"http://x/a", "http://x/b", "http://x/a", "http://x/b"},
+			expected: []string{"http://x/a", "http://x/b"},
+		},
+		{
+			name:     "self redirect after a hop",
+			urls:     []string{"http://x/start", "http://x/self", "http://x/self"},
+			expected: []string{"http://x/self"},
+		},
+		{
+			name:     "long chain without repeats",
+			urls:     []string{"http://x/1", "http://x/2", "http://x/3"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &tooManyRedirectsError{Count: len(tt.urls) - 1, URLs: tt.urls}
+			loop := err.LoopURLs()
+			if strings.Join(loop, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected loop %v, got %v", tt.expected, loop)
+			}
+		})
+	}
+}
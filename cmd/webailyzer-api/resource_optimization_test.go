@@ -14,6 +14,10 @@ func TestResourceOptimizationIntegration(t *testing.T) {
 	// Initialize optimizations
 	optimizeGCSettings()
 	initHTTPClient()
+	// The shared engine lives for the whole process, so load it before measuring
+	if _, err := getWappalyzer(); err != nil {
+		t.Fatalf("Failed to initialize wappalyzer: %v", err)
+	}
 	
 	// Get initial memory stats
 	runtime.GC()
@@ -61,7 +65,11 @@ func TestResourceOptimizationIntegration(t *testing.T) {
 	t.Logf("GC runs: %d", finalStats.NumGC-initialStats.NumGC)
 	
 	// Memory should not have grown excessively (allow for some growth)
-	memoryGrowth := finalStats.Alloc - initialStats.Alloc
+	// Alloc is unsigned, so a shrinking heap counts as no growth rather than wrapping around
+	var memoryGrowth uint64
+	if finalStats.Alloc > initialStats.Alloc {
+		memoryGrowth = finalStats.Alloc - initialStats.Alloc
+	}
 	maxAllowedGrowth := uint64(10 * 1024 * 1024) // 10MB
 	
 	if memoryGrowth > maxAllowedGrowth {
@@ -98,8 +106,9 @@ func TestHTTPClientConnectionPooling(t *testing.T) {
 		t.Errorf("Expected IdleConnTimeout=90s, got %v", transport.IdleConnTimeout)
 	}
 	
-	if transport.ResponseHeaderTimeout != 10*time.Second {
-		t.Errorf("Expected ResponseHeaderTimeout=10s, got %v", transport.ResponseHeaderTimeout)
+	// Derived per fetch from the analysis budget instead
+	if transport.ResponseHeaderTimeout != 0 {
+		t.Errorf("Expected no fixed ResponseHeaderTimeout, got %v", transport.ResponseHeaderTimeout)
 	}
 	
 	if !transport.ForceAttemptHTTP2 {
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Backoff bounds for retried fetches; variables so tests can shorten them
+var (
+	fetchRetryBaseDelay = 250 * time.Millisecond
+	fetchRetryMaxDelay  = 2 * time.Second
+)
+
+// errResponseHeaderTimeout fails an attempt whose response headers did not arrive in time
+var errResponseHeaderTimeout = errors.New("timeout awaiting response headers")
+
+// responseHeaderTimeout is how long a fetch attempt waits for response headers: half
+// the analysis budget, leaving room to retry a stalled attempt or read the body
+func responseHeaderTimeout(budget time.Duration) time.Duration {
+	return budget / 2
+}
+
+// fetchWithRetry performs the request, retrying transient failures with exponential backoff.
+// Connection resets, timeouts and 5xx responses are retried; DNS failures and 4xx are not.
+// Each attempt waits at most headerTimeout for response headers, or as long as ctx allows
+// when it is zero. The last response or error is returned once attempts are exhausted.
+func fetchWithRetry(ctx context.Context, client *http.Client, req *http.Request, maxAttempts int, headerTimeout time.Duration) (*http.Response, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	requestID := ""
+	if id := ctx.Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	for attempt := 1; ; attempt++ {
+		// Only the redirect hops and phase timings of the final attempt should be reported
+		if rec, ok := ctx.Value(redirectRecorderKey{}).(*redirectRecorder); ok {
+			rec.hops = nil
+		}
+		if tr, ok := ctx.Value(fetchTracerKey{}).(*fetchTracer); ok {
+			tr.reset()
+		}
+
+		attemptStart := time.Now()
+		resp, err := doWithHeaderTimeout(ctx, client, req, headerTimeout)
+		attemptDuration := time.Since(attemptStart)
+
+		retryable := false
+		if err != nil {
+			retryable = isRetryableFetchError(ctx, err)
+		} else if resp.StatusCode >= 500 {
+			retryable = true
+		}
+
+		if !retryable || attempt >= maxAttempts {
+			return resp, err
+		}
+
+		delay := retryDelay(attempt)
+		// Skip the retry if the remaining budget could not fit the backoff plus
+		// another attempt as long as the one that just failed
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay+attemptDuration {
+			return resp, err
+		}
+
+		fields := logrus.Fields{
+			"request_id": requestID,
+			"url":        req.URL.String(),
+			"attempt":    attempt,
+			"delay_ms":   delay.Milliseconds(),
+		}
+		if err != nil {
+			fields["error"] = err
+		} else {
+			fields["status_code"] = resp.StatusCode
+			resp.Body.Close()
+		}
+		logger.WithFields(fields).Warn("Transient fetch failure, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns the exponential backoff delay before the given retry
+func retryDelay(attempt int) time.Duration {
+	delay := fetchRetryBaseDelay << (attempt - 1)
+	if delay > fetchRetryMaxDelay || delay <= 0 {
+		delay = fetchRetryMaxDelay
+	}
+	return delay
+}
+
+// doWithHeaderTimeout sends a single attempt, abandoning it if the response headers
+// take longer than headerTimeout. The body of a response stays readable until closed.
+func doWithHeaderTimeout(ctx context.Context, client *http.Client, req *http.Request, headerTimeout time.Duration) (*http.Response, error) {
+	if headerTimeout <= 0 {
+		return client.Do(req.Clone(ctx))
+	}
+
+	attemptCtx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(headerTimeout, func() { cancel(errResponseHeaderTimeout) })
+	resp, err := client.Do(req.Clone(attemptCtx))
+	if !timer.Stop() && err == nil {
+		// The headers arrived just as the timer fired, after the body was cut off
+		resp.Body.Close()
+		err = errResponseHeaderTimeout
+	}
+	if err != nil {
+		if errors.Is(context.Cause(attemptCtx), errResponseHeaderTimeout) && ctx.Err() == nil {
+			err = fmt.Errorf("%w after %v", errResponseHeaderTimeout, headerTimeout)
+		}
+		cancel(nil)
+		return nil, err
+	}
+
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: func() { cancel(nil) }}
+	return resp, nil
+}
+
+// cancelOnClose releases an attempt's context once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// isRetryableFetchError reports whether a client.Do error is worth retrying
+func isRetryableFetchError(ctx context.Context, err error) bool {
+	// The overall request budget is spent or the caller went away
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// NXDOMAIN will not resolve on a second try
+		return !dnsErr.IsNotFound && (dnsErr.IsTimeout || dnsErr.IsTemporary)
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.Is(err, errResponseHeaderTimeout) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// shortenRetryBackoff makes retry tests fast and restores the defaults afterwards
+func shortenRetryBackoff(t *testing.T) {
+	base, max := fetchRetryBaseDelay, fetchRetryMaxDelay
+	fetchRetryBaseDelay = 10 * time.Millisecond
+	fetchRetryMaxDelay = 50 * time.Millisecond
+	t.Cleanup(func() {
+		fetchRetryBaseDelay, fetchRetryMaxDelay = base, max
+	})
+}
+
+// flakyServer fails the first failures requests using fail, then serves a page
+func flakyServer(failures int32, fail func(w http.ResponseWriter)) (*httptest.Server, *int32) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= failures {
+			fail(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Recovered</body></html>`))
+	}))
+	return server, &attempts
+}
+
+func TestFetchWithRetryRecoversFromServerErrors(t *testing.T) {
+	shortenRetryBackoff(t)
+
+	server, attempts := flakyServer(2, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := fetchWithRetry(context.Background(), createHTTPClient(), req, 3, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 after retries, got %d", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(attempts); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestFetchWithRetryReturnsLastServerErrorWhenExhausted(t *testing.T) {
+	shortenRetryBackoff(t)
+
+	server, attempts := flakyServer(5, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := fetchWithRetry(context.Background(), createHTTPClient(), req, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected final 502 response, got %d", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(attempts); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestFetchWithRetryRecoversFromConnectionReset(t *testing.T) {
+	shortenRetryBackoff(t)
+
+	server, attempts := flakyServer(1, func(w http.ResponseWriter) {
+		// Drop the connection without sending a response
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			if tcpConn, ok := conn.(*net.TCPConn); ok {
+				tcpConn.SetLinger(0)
+			}
+			conn.Close()
+		}
+	})
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := fetchWithRetry(context.Background(), createHTTPClient(), req, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 after retry, got %d", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(attempts); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestFetchWithRetryDoesNotRetryClientErrors(t *testing.T) {
+	shortenRetryBackoff(t)
+
+	server, attempts := flakyServer(5, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := fetchWithRetry(context.Background(), createHTTPClient(), req, 3, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 to be returned as is, got %d", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(attempts); n != 1 {
+		t.Errorf("4xx responses should not be retried, got %d attempts", n)
+	}
+}
+
+func TestFetchWithRetryRespectsCancellation(t *testing.T) {
+	base := fetchRetryBaseDelay
+	fetchRetryBaseDelay = time.Second
+	defer func() { fetchRetryBaseDelay = base }()
+
+	server, attempts := flakyServer(5, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	start := time.Now()
+	_, err := fetchWithRetry(ctx, createHTTPClient(), req, 3, 0)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("cancellation should interrupt the backoff, took %v", elapsed)
+	}
+	if n := atomic.LoadInt32(attempts); n != 1 {
+		t.Errorf("expected 1 attempt before cancellation, got %d", n)
+	}
+}
+
+func TestFetchWithRetryRetriesStalledHeaders(t *testing.T) {
+	shortenRetryBackoff(t)
+
+	// The first attempt stalls before sending headers; the retry answers at once
+	server, attempts := flakyServer(1, func(w http.ResponseWriter) {
+		time.Sleep(300 * time.Millisecond)
+	})
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := fetchWithRetry(context.Background(), createHTTPClient(), req, 2, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The body must stay readable after the header timer is stopped
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || !strings.Contains(string(body), "Recovered") {
+		t.Errorf("expected the recovered page, got %q (err %v)", body, err)
+	}
+	if n := atomic.LoadInt32(attempts); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestFetchWithRetryHeaderTimeout(t *testing.T) {
+	server, _ := flakyServer(5, func(w http.ResponseWriter) {
+		time.Sleep(300 * time.Millisecond)
+	})
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	_, err := fetchWithRetry(context.Background(), createHTTPClient(), req, 1, 100*time.Millisecond)
+	if !errors.Is(err, errResponseHeaderTimeout) {
+		t.Errorf("expected errResponseHeaderTimeout, got %v", err)
+	}
+}
+
+func TestIsRetryableFetchError(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"dns not found", &net.DNSError{Err: "no such host", Name: "missing.example", IsNotFound: true}, false},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", Name: "slow.example", IsTimeout: true}, true},
+		{"generic error", errors.New("stopped after 10 redirects"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableFetchError(ctx, tt.err); got != tt.expected {
+				t.Errorf("isRetryableFetchError(%v) = %v, want %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if isRetryableFetchError(cancelled, &net.DNSError{IsTimeout: true}) {
+		t.Error("errors after the context is done should not be retried")
+	}
+}
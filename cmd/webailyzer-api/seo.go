@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// SEO findings reported by analyzeSEO
+const (
+	seoFindingNoIndex              = "noindex"
+	seoFindingNoFollow             = "nofollow"
+	seoFindingDuplicateTitle       = "duplicate_title"
+	seoFindingDuplicateDescription = "duplicate_meta_description"
+	seoFindingMissingFavicon       = "missing_favicon"
+	seoFindingMissingTouchIcon     = "missing_apple_touch_icon"
+	seoFindingInvalidHreflang      = "invalid_hreflang"
+	seoFindingConflictingHreflang  = "conflicting_hreflang"
+	seoFindingMissingXDefault      = "missing_hreflang_x_default"
+	seoFindingMissingSelfHreflang  = "missing_hreflang_self_reference"
+	seoFindingInvalidSchema        = "invalid_structured_data"
+)
+
+// hreflangPattern matches a language code, with an optional script and region, such
+// as en, en-GB, zh-Hant or es-419
+var hreflangPattern = regexp.MustCompile(`(?i)^[a-z]{2,3}(-[a-z]{4})?(-([a-z]{2}|[0-9]{3}))?$`)
+
+// robotsMetaNames are the meta tag names that carry crawler directives
+var robotsMetaNames = toSet([]string{"robots", "googlebot", "bingbot"})
+
+// SEOReport describes how search engines see the page
+type SEOReport struct {
+	Findings    []SEOFinding     `json:"findings"`
+	Robots      RobotsDirectives `json:"robots"`
+	Title       MetaTag          `json:"title"`
+	Description MetaTag          `json:"meta_description"`
+	Icons       IconReport       `json:"icons"`
+	// Hreflang is nil when the page declares no language alternates
+	Hreflang       *HreflangReport      `json:"hreflang,omitempty"`
+	StructuredData []StructuredDataItem `json:"structured_data"`
+}
+
+// StructuredDataItem is one JSON-LD item, checked for the properties search engines
+// need to show it as a rich result
+type StructuredDataItem struct {
+	Type   string   `json:"type"`
+	Valid  bool     `json:"valid"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+// HreflangReport lists the page's <link rel="alternate" hreflang> declarations
+type HreflangReport struct {
+	// Locales are the declared hreflang values, in document order without repeats
+	Locales []string       `json:"locales"`
+	Links   []HreflangLink `json:"links"`
+}
+
+// HreflangLink is one language alternate, with href as written
+type HreflangLink struct {
+	Hreflang string `json:"hreflang"`
+	Href     string `json:"href"`
+}
+
+// IconReport gives the href of each icon the page declares, as written
+type IconReport struct {
+	Favicon        string `json:"favicon,omitempty"`
+	AppleTouchIcon string `json:"apple_touch_icon,omitempty"`
+}
+
+// MetaTag is a tag search engines show in their results, such as the title
+type MetaTag struct {
+	// Content is the first tag's text, which search engines use
+	Content string   `json:"content"`
+	Count   int      `json:"count"`
+	Issues  []string `json:"issues,omitempty"`
+}
+
+// SEOFinding is one problem that keeps the page from ranking as it could
+type SEOFinding struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// RobotsDirectives are the crawler directives the page declares
+type RobotsDirectives struct {
+	// Meta holds the content of each robots meta tag
+	Meta []string `json:"meta,omitempty"`
+	// Header holds each X-Robots-Tag response header
+	Header []string `json:"header,omitempty"`
+	// Indexable is unset when either source tells search engines not to index the page
+	Indexable bool `json:"indexable"`
+	NoFollow  bool `json:"nofollow"`
+}
+
+// seoMarkup is what analyzeSEO reads from the page
+type seoMarkup struct {
+	RobotsMeta   []string
+	Titles       []string
+	Descriptions []string
+	Icons        IconReport
+	Hreflang     []HreflangLink
+	// JSONLD holds the text of each <script type="application/ld+json">
+	JSONLD []string
+}
+
+// scanSEOMarkup tokenizes the page for the tags search engines read. A <title> inside
+// inline SVG names the graphic, not the page, and is skipped.
+func scanSEOMarkup(body []byte) seoMarkup {
+	var (
+		markup   seoMarkup
+		svg      int // depth of open <svg> elements
+		inTitle  bool
+		inJSONLD bool
+	)
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF at the end of the body; the tokenizer recovers from malformed markup itself
+			return markup
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "svg":
+				svg++
+			case "title":
+				if svg == 0 {
+					// The title's text follows as one token, or none for an empty title
+					markup.Titles = append(markup.Titles, "")
+					inTitle = true
+				}
+			case "link":
+				attrs := tagAttrs(z, hasAttr)
+				if hreflang, ok := attrs["hreflang"]; ok && hasToken(attrs["rel"], "alternate") {
+					markup.Hreflang = append(markup.Hreflang, HreflangLink{Hreflang: strings.TrimSpace(hreflang), Href: strings.TrimSpace(attrs["href"])})
+				}
+				// rel="shortcut icon" is an older spelling of rel="icon"; the first icon wins
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					switch {
+					case rel == "icon" && markup.Icons.Favicon == "":
+						markup.Icons.Favicon = attrs["href"]
+					case (rel == "apple-touch-icon" || rel == "apple-touch-icon-precomposed") && markup.Icons.AppleTouchIcon == "":
+						markup.Icons.AppleTouchIcon = attrs["href"]
+					}
+				}
+			case "script":
+				mediaType, _, _ := strings.Cut(tagAttrs(z, hasAttr)["type"], ";")
+				if strings.EqualFold(strings.TrimSpace(mediaType), "application/ld+json") {
+					markup.JSONLD = append(markup.JSONLD, "")
+					inJSONLD = true
+				}
+			case "meta":
+				attrs := tagAttrs(z, hasAttr)
+				switch name := strings.ToLower(attrs["name"]); {
+				case robotsMetaNames[name]:
+					markup.RobotsMeta = append(markup.RobotsMeta, attrs["content"])
+				case name == "description":
+					markup.Descriptions = append(markup.Descriptions, strings.TrimSpace(attrs["content"]))
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "svg":
+				if svg > 0 {
+					svg--
+				}
+			case "title":
+				inTitle = false
+			case "script":
+				inJSONLD = false
+			}
+		case html.TextToken:
+			if inTitle {
+				markup.Titles[len(markup.Titles)-1] = strings.Join(strings.Fields(string(z.Text())), " ")
+			}
+			if inJSONLD {
+				markup.JSONLD[len(markup.JSONLD)-1] = string(z.Text())
+			}
+		}
+	}
+}
+
+// analyzeSEO checks the page's markup and headers for problems with search visibility.
+// pageURL resolves relative links and is empty for inline pages.
+func analyzeSEO(pageURL string, header http.Header, body []byte) *SEOReport {
+	markup := scanSEOMarkup(body)
+
+	report := &SEOReport{}
+	report.Robots, report.Findings = robotsFindings(markup.RobotsMeta, header.Values("X-Robots-Tag"))
+
+	var finding *SEOFinding
+	report.Title, finding = metaTag(markup.Titles, "<title>", seoFindingDuplicateTitle)
+	if finding != nil {
+		report.Findings = append(report.Findings, *finding)
+	}
+	report.Description, finding = metaTag(markup.Descriptions, `<meta name="description">`, seoFindingDuplicateDescription)
+	if finding != nil {
+		report.Findings = append(report.Findings, *finding)
+	}
+
+	report.Icons = markup.Icons
+	if report.Icons.Favicon == "" {
+		report.Findings = append(report.Findings, SEOFinding{
+			Type:     seoFindingMissingFavicon,
+			Severity: "low",
+			Message:  `The page declares no <link rel="icon">, so search results and bookmarks rely on /favicon.ico being present`,
+		})
+	}
+	if report.Icons.AppleTouchIcon == "" {
+		report.Findings = append(report.Findings, SEOFinding{
+			Type:     seoFindingMissingTouchIcon,
+			Severity: "low",
+			Message:  `The page declares no <link rel="apple-touch-icon">, so home screen shortcuts on iOS show a screenshot instead of an icon`,
+		})
+	}
+
+	if len(markup.Hreflang) > 0 {
+		var findings []SEOFinding
+		report.Hreflang, findings = analyzeHreflang(pageURL, markup.Hreflang)
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	report.StructuredData = []StructuredDataItem{}
+	for _, block := range markup.JSONLD {
+		report.StructuredData = append(report.StructuredData, validateJSONLD(block)...)
+	}
+	for _, item := range report.StructuredData {
+		if !item.Valid {
+			report.Findings = append(report.Findings, SEOFinding{
+				Type:     seoFindingInvalidSchema,
+				Severity: "medium",
+				Message:  fmt.Sprintf("%s structured data is not eligible for rich results: %s", schemaName(item.Type), strings.Join(item.Issues, "; ")),
+			})
+		}
+	}
+	return report
+}
+
+// analyzeHreflang validates the page's language alternates. Search engines only trust
+// a set of alternates that includes the page itself, and fall back to x-default for
+// visitors whose language is not listed. The self-reference is only checked when
+// pageURL is known.
+func analyzeHreflang(pageURL string, links []HreflangLink) (*HreflangReport, []SEOFinding) {
+	report := &HreflangReport{Locales: []string{}, Links: links}
+	var findings []SEOFinding
+
+	base, _ := url.Parse(pageURL)
+	self := base != nil && base.Host != ""
+	hrefs := make(map[string]string) // lowercased hreflang to resolved href
+	hasXDefault, hasSelf := false, false
+	for _, link := range links {
+		code := strings.ToLower(link.Hreflang)
+		href := resolveHref(base, link.Href)
+
+		if previous, seen := hrefs[code]; seen {
+			if previous != href {
+				findings = append(findings, SEOFinding{
+					Type:     seoFindingConflictingHreflang,
+					Severity: "medium",
+					Message:  fmt.Sprintf("hreflang %q points to both %s and %s; keep one URL per language", link.Hreflang, previous, href),
+				})
+			}
+			continue
+		}
+		hrefs[code] = href
+		report.Locales = append(report.Locales, link.Hreflang)
+
+		switch {
+		case code == "x-default":
+			hasXDefault = true
+		case !hreflangPattern.MatchString(code) || strings.HasSuffix(code, "-uk"):
+			// UK is not an ISO 3166 region; the United Kingdom is GB
+			findings = append(findings, SEOFinding{
+				Type:     seoFindingInvalidHreflang,
+				Severity: "medium",
+				Message:  fmt.Sprintf("hreflang %q is not a language code such as en or en-GB, so search engines ignore it", link.Hreflang),
+			})
+		}
+		if self && href == resolveHref(base, "") {
+			hasSelf = true
+		}
+	}
+
+	if !hasXDefault {
+		findings = append(findings, SEOFinding{
+			Type:     seoFindingMissingXDefault,
+			Severity: "low",
+			Message:  `No hreflang="x-default" alternate, so visitors whose language is not listed get no default page`,
+		})
+	}
+	if self && !hasSelf {
+		findings = append(findings, SEOFinding{
+			Type:     seoFindingMissingSelfHreflang,
+			Severity: "medium",
+			Message:  "The hreflang alternates do not include this page, so search engines may ignore them; list the page under its own language",
+		})
+	}
+	return report, findings
+}
+
+// resolveHref resolves href against base and drops the fragment, so links to the same
+// page compare equal. Without a base href is returned as written.
+func resolveHref(base *url.URL, href string) string {
+	if base == nil || base.Host == "" {
+		return href
+	}
+	resolved, err := base.Parse(href)
+	if err != nil {
+		return href
+	}
+	resolved.Fragment = ""
+	resolved.Host = strings.ToLower(resolved.Host)
+	return resolved.String()
+}
+
+// hasToken reports whether the space-separated list contains token, ignoring case
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// metaTag summarizes every occurrence of a tag that a page should have once. More
+// than one is reported, since crawlers pick one and duplicates often come from a
+// layout and a page template both adding the tag.
+func metaTag(values []string, tag, findingType string) (MetaTag, *SEOFinding) {
+	meta := MetaTag{Count: len(values)}
+	if len(values) > 0 {
+		meta.Content = values[0]
+	}
+	if len(values) < 2 {
+		return meta, nil
+	}
+	count := fmt.Sprintf("%d %s tags", len(values), tag)
+	meta.Issues = append(meta.Issues, count+"; keep only one")
+	return meta, &SEOFinding{
+		Type:     findingType,
+		Severity: "medium",
+		Message:  fmt.Sprintf("The page has %s, so search engines may show a different one than intended", count),
+	}
+}
+
+// robotsFindings reads noindex and nofollow from robots meta tags and X-Robots-Tag
+// headers. Directives scoped to one crawler, such as "googlebot: noindex", count too,
+// since they hide the page from that search engine.
+func robotsFindings(meta, header []string) (RobotsDirectives, []SEOFinding) {
+	robots := RobotsDirectives{Meta: meta, Header: header}
+	noIndex := directiveSources(meta, header, "noindex")
+	noFollow := directiveSources(meta, header, "nofollow")
+	robots.Indexable = len(noIndex) == 0
+	robots.NoFollow = len(noFollow) > 0
+
+	findings := []SEOFinding{}
+	if len(noIndex) > 0 {
+		findings = append(findings, SEOFinding{
+			Type:     seoFindingNoIndex,
+			Severity: "high",
+			Message:  fmt.Sprintf("noindex is set by the %s, so search engines leave the page out of their results", strings.Join(noIndex, " and the ")),
+		})
+	}
+	if len(noFollow) > 0 {
+		findings = append(findings, SEOFinding{
+			Type:     seoFindingNoFollow,
+			Severity: "high",
+			Message:  fmt.Sprintf("nofollow is set by the %s, so search engines do not follow the page's links", strings.Join(noFollow, " and the ")),
+		})
+	}
+	return robots, findings
+}
+
+// directiveSources names where directive is declared, checking the meta tags and then
+// the header. "none" stands for both noindex and nofollow.
+func directiveSources(meta, header []string, directive string) []string {
+	var sources []string
+	if hasDirective(meta, directive) {
+		sources = append(sources, "robots meta tag")
+	}
+	if hasDirective(header, directive) {
+		sources = append(sources, "X-Robots-Tag header")
+	}
+	return sources
+}
+
+// hasDirective reports whether any of the comma-separated directive lists contains directive
+func hasDirective(values []string, directive string) bool {
+	for _, value := range values {
+		for _, part := range strings.Split(strings.ToLower(value), ",") {
+			part = strings.TrimSpace(part)
+			// Drop a crawler prefix such as "googlebot:"; unavailable_after takes a date
+			// after its colon and is no prefix
+			if crawler, rest, ok := strings.Cut(part, ":"); ok && crawler != "unavailable_after" && !strings.Contains(crawler, " ") {
+				part = strings.TrimSpace(rest)
+			}
+			if part == directive || part == "none" {
+				return true
+			}
+		}
+	}
+	return false
+}
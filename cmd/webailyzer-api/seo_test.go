@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// icons declares both icons, for pages in tests that check something else
+const icons = `<link rel="icon" href="/favicon.svg"><link rel="apple-touch-icon" href="/touch.png">`
+
+// findingTypes returns the SEO finding types in report order
+func findingTypes(report *SEOReport) []string {
+	types := []string{}
+	for _, f := range report.Findings {
+		types = append(types, f.Type)
+	}
+	return types
+}
+
+func TestAnalyzeSEORobotsDirectives(t *testing.T) {
+	tests := []struct {
+		name      string
+		meta      string
+		header    []string
+		indexable bool
+		findings  []string
+		sources   string
+	}{
+		{name: "indexable", meta: `<meta name="robots" content="index, follow">`, header: []string{"noarchive"}, indexable: true, findings: []string{}},
+		{
+			name:     "meta only",
+			meta:     `<meta name="ROBOTS" content="NOINDEX">`,
+			findings: []string{seoFindingNoIndex},
+			sources:  "set by the robots meta tag, so",
+		},
+		{
+			name:     "header only",
+			header:   []string{"googlebot: noindex, nofollow"},
+			findings: []string{seoFindingNoIndex, seoFindingNoFollow},
+			sources:  "set by the X-Robots-Tag header, so",
+		},
+		{
+			name:     "meta and header",
+			meta:     `<meta name="googlebot" content="noindex,nofollow">`,
+			header:   []string{"unavailable_after: 25 Jun 2030 15:00:00 PST", "none"},
+			findings: []string{seoFindingNoIndex, seoFindingNoFollow},
+			sources:  "set by the robots meta tag and the X-Robots-Tag header, so",
+		},
+		{
+			name:      "nofollow only",
+			meta:      `<meta name="robots" content="nofollow">`,
+			indexable: true,
+			findings:  []string{seoFindingNoFollow},
+			sources:   "set by the robots meta tag, so",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, value := range tt.header {
+				header.Add("X-Robots-Tag", value)
+			}
+			report := analyzeSEO("", header, []byte(`<html><head>`+icons+tt.meta+`</head><body></body></html>`))
+
+			if report.Robots.Indexable != tt.indexable {
+				t.Errorf("expected indexable %v, got %+v", tt.indexable, report.Robots)
+			}
+			if got := findingTypes(report); !reflect.DeepEqual(got, tt.findings) {
+				t.Errorf("expected findings %v, got %+v", tt.findings, report.Findings)
+			}
+			for _, f := range report.Findings {
+				if f.Severity != "high" || !strings.Contains(f.Message, tt.sources) {
+					t.Errorf("expected a high severity finding %q, got %+v", tt.sources, f)
+				}
+			}
+			if !reflect.DeepEqual(report.Robots.Header, header.Values("X-Robots-Tag")) {
+				t.Errorf("expected the X-Robots-Tag values reported, got %v", report.Robots.Header)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSEODuplicateTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		head         string
+		titles       int
+		descriptions int
+		findings     []string
+	}{
+		{
+			name:         "single tags",
+			head:         `<title>Corner Bakery</title><meta name="description" content="Fresh bread daily">`,
+			titles:       1,
+			descriptions: 1,
+			findings:     []string{},
+		},
+		{
+			name:     "missing tags",
+			findings: []string{},
+		},
+		{
+			name: "duplicated tags",
+			head: `<title>Corner Bakery</title><title>Home</title><title></title>
+				<meta name="description" content="Fresh bread daily"><meta name="Description" content="Bakery">`,
+			titles:       3,
+			descriptions: 2,
+			findings:     []string{seoFindingDuplicateTitle, seoFindingDuplicateDescription},
+		},
+		{
+			name:         "svg titles",
+			head:         `<title>Corner Bakery</title></head><body><svg><title>Cart icon</title></svg>`,
+			titles:       1,
+			descriptions: 0,
+			findings:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeSEO("", nil, []byte(`<html><head>`+icons+tt.head+`</head><body></body></html>`))
+			if report.Title.Count != tt.titles || report.Description.Count != tt.descriptions {
+				t.Errorf("expected %d titles and %d descriptions, got %+v and %+v", tt.titles, tt.descriptions, report.Title, report.Description)
+			}
+			if got := findingTypes(report); !reflect.DeepEqual(got, tt.findings) {
+				t.Errorf("expected findings %v, got %+v", tt.findings, report.Findings)
+			}
+			if tt.titles > 1 && !reflect.DeepEqual(report.Title.Issues, []string{"3 <title> tags; keep only one"}) {
+				t.Errorf("expected the title count in its issues, got %v", report.Title.Issues)
+			}
+			if tt.titles > 0 && report.Title.Content != "Corner Bakery" {
+				t.Errorf("expected the first title, got %q", report.Title.Content)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSEOIcons(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     string
+		icons    IconReport
+		findings []string
+	}{
+		{
+			name:     "both icons",
+			head:     `<link rel="shortcut icon" href="/favicon.ico"><link rel="icon" href="/favicon.svg"><link rel="Apple-Touch-Icon" href="/touch.png">`,
+			icons:    IconReport{Favicon: "/favicon.ico", AppleTouchIcon: "/touch.png"},
+			findings: []string{},
+		},
+		{
+			name:     "precomposed touch icon only",
+			head:     `<link rel="apple-touch-icon-precomposed" href="/touch.png"><link rel="stylesheet" href="/site.css">`,
+			icons:    IconReport{AppleTouchIcon: "/touch.png"},
+			findings: []string{seoFindingMissingFavicon},
+		},
+		{
+			name:     "no icons",
+			head:     `<link rel="canonical" href="https://bakery.example/">`,
+			findings: []string{seoFindingMissingFavicon, seoFindingMissingTouchIcon},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeSEO("", nil, []byte(`<html><head><title>Bakery</title>`+tt.head+`</head><body></body></html>`))
+			if report.Icons != tt.icons {
+				t.Errorf("expected icons %+v, got %+v", tt.icons, report.Icons)
+			}
+			if got := findingTypes(report); !reflect.DeepEqual(got, tt.findings) {
+				t.Errorf("expected findings %v, got %+v", tt.findings, report.Findings)
+			}
+			for _, f := range report.Findings {
+				if f.Severity != "low" {
+					t.Errorf("expected missing icons to be low severity, got %+v", f)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeSEOHreflang(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageURL  string
+		links    string
+		locales  []string
+		findings []string
+	}{
+		{
+			name:    "valid alternates",
+			pageURL: "https://bakery.example/en/#menu",
+			links: `<link rel="alternate" hreflang="en" href="/en/"><link rel="alternate" hreflang="de-AT" href="https://bakery.example/de-at/">
+				<link rel="alternate" hreflang="zh-Hant" href="/zh-hant/"><link rel="alternate" hreflang="es-419" href="/es/">
+				<link rel="alternate" hreflang="x-default" href="/">`,
+			locales:  []string{"en", "de-AT", "zh-Hant", "es-419", "x-default"},
+			findings: []string{},
+		},
+		{
+			name:    "invalid codes",
+			pageURL: "https://bakery.example/en/",
+			links: `<link rel="alternate" hreflang="en" href="/en/"><link rel="alternate" hreflang="english" href="/en/">
+				<link rel="alternate" hreflang="en-UK" href="/uk/"><link rel="alternate" hreflang="x-default" href="/">`,
+			locales:  []string{"en", "english", "en-UK", "x-default"},
+			findings: []string{seoFindingInvalidHreflang, seoFindingInvalidHreflang},
+		},
+		{
+			name:     "missing x-default",
+			pageURL:  "https://bakery.example/en/",
+			links:    `<link rel="alternate" hreflang="en" href="https://bakery.example/en/"><link rel="alternate" hreflang="fr" href="/fr/">`,
+			locales:  []string{"en", "fr"},
+			findings: []string{seoFindingMissingXDefault},
+		},
+		{
+			name:    "conflicting entries",
+			pageURL: "https://bakery.example/en/",
+			links: `<link rel="alternate" hreflang="en" href="/en/"><link rel="alternate" hreflang="EN" href="/en/">
+				<link rel="alternate" hreflang="en" href="/english/"><link rel="alternate" hreflang="x-default" href="/">`,
+			locales:  []string{"en", "x-default"},
+			findings: []string{seoFindingConflictingHreflang},
+		},
+		{
+			name:     "page missing from its alternates",
+			pageURL:  "https://bakery.example/en/",
+			links:    `<link rel="alternate" hreflang="fr" href="/fr/"><link rel="alternate" hreflang="x-default" href="/">`,
+			locales:  []string{"fr", "x-default"},
+			findings: []string{seoFindingMissingSelfHreflang},
+		},
+		{
+			name:     "inline page",
+			links:    `<link rel="alternate" hreflang="fr" href="/fr/"><link rel="alternate" hreflang="x-default" href="/">`,
+			locales:  []string{"fr", "x-default"},
+			findings: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeSEO(tt.pageURL, nil, []byte(`<html><head><title>Bakery</title>`+icons+tt.links+`</head><body></body></html>`))
+			if report.Hreflang == nil || !reflect.DeepEqual(report.Hreflang.Locales, tt.locales) {
+				t.Fatalf("expected locales %v, got %+v", tt.locales, report.Hreflang)
+			}
+			if got := findingTypes(report); !reflect.DeepEqual(got, tt.findings) {
+				t.Errorf("expected findings %v, got %+v", tt.findings, report.Findings)
+			}
+		})
+	}
+
+	// Alternate stylesheets and feeds are not language alternates
+	report := analyzeSEO("", nil, []byte(`<link rel="alternate" type="application/rss+xml" href="/feed"><link rel="stylesheet" hreflang="en" href="/a.css">`))
+	if report.Hreflang != nil {
+		t.Errorf("expected no hreflang section, got %+v", report.Hreflang)
+	}
+}
+
+func TestAnalyzeSEOStructuredData(t *testing.T) {
+	tests := []struct {
+		name   string
+		jsonLD string
+		items  []StructuredDataItem
+	}{
+		{
+			name: "complete items",
+			jsonLD: `{"@context": "https://schema.org", "@graph": [
+				{"@type": "Organization", "name": "Corner Bakery", "url": "https://bakery.example"},
+				{"@type": "Product", "name": "Sourdough", "offers": {"@type": "Offer", "price": "6.50", "priceCurrency": "EUR"}},
+				{"@type": "BreadcrumbList", "itemListElement": [
+					{"@type": "ListItem", "position": 1, "name": "Home", "item": "https://bakery.example/"},
+					{"@type": "ListItem", "position": 2, "item": {"@id": "https://bakery.example/bread", "name": "Bread"}}]}]}`,
+			items: []StructuredDataItem{{Type: "Organization", Valid: true}, {Type: "Product", Valid: true}, {Type: "BreadcrumbList", Valid: true}},
+		},
+		{
+			name:   "incomplete article",
+			jsonLD: `{"@context": "https://schema.org", "@type": ["BlogPosting"], "headline": "New rye", "author": ""}`,
+			items:  []StructuredDataItem{{Type: "BlogPosting", Issues: []string{"missing author", "missing datePublished"}}},
+		},
+		{
+			name: "incomplete products",
+			jsonLD: `[{"@type": "https://schema.org/Product", "name": "Rye", "offers": [{"price": 5}, {"priceCurrency": "EUR"}]},
+				{"@type": "Product", "offers": {"@type": "AggregateOffer", "lowPrice": "2.00"}}, {"@type": "Product", "name": "Bun"}]`,
+			items: []StructuredDataItem{
+				{Type: "Product", Issues: []string{"offers 2 missing price"}},
+				{Type: "Product", Issues: []string{"missing name"}},
+				{Type: "Product", Issues: []string{"missing offers"}},
+			},
+		},
+		{
+			name:   "incomplete breadcrumbs",
+			jsonLD: `{"@type": "BreadcrumbList", "itemListElement": [{"name": "Home"}]}`,
+			items:  []StructuredDataItem{{Type: "BreadcrumbList", Issues: []string{"itemListElement 1 missing position"}}},
+		},
+		{
+			name:   "unchecked type",
+			jsonLD: `{"@type": "Bakery", "name": "Corner Bakery"}`,
+			items:  []StructuredDataItem{{Type: "Bakery", Valid: true}},
+		},
+		{
+			name:   "no type",
+			jsonLD: `{"name": "Corner Bakery"}`,
+			items:  []StructuredDataItem{{Issues: []string{"missing @type"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeSEO("", nil, []byte(`<html><head><title>Bakery</title>`+icons+
+				`<script type="application/ld+json">`+tt.jsonLD+`</script></head><body></body></html>`))
+			if !reflect.DeepEqual(report.StructuredData, tt.items) {
+				t.Errorf("expected %+v, got %+v", tt.items, report.StructuredData)
+			}
+			invalid := 0
+			for _, item := range tt.items {
+				if !item.Valid {
+					invalid++
+				}
+			}
+			if len(report.Findings) != invalid {
+				t.Errorf("expected %d invalid_structured_data findings, got %+v", invalid, report.Findings)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSEOStructuredDataInvalidJSON(t *testing.T) {
+	report := analyzeSEO("", nil, []byte(`<script type="application/ld+json">{"@type": "Product",</script>
+		<script type="text/javascript">{"@type": "Product"}</script>`))
+	if len(report.StructuredData) != 1 || report.StructuredData[0].Valid || !strings.HasPrefix(report.StructuredData[0].Issues[0], "invalid JSON") {
+		t.Fatalf("expected one invalid JSON-LD block, got %+v", report.StructuredData)
+	}
+	if !containsString(findingTypes(report), seoFindingInvalidSchema) {
+		t.Errorf("expected an invalid_structured_data finding, got %+v", report.Findings)
+	}
+}
+
+func TestAnalyzeHandlerReportsSEO(t *testing.T) {
+	rr := runPipeline(t, map[string]interface{}{
+		"html":      `<html><head>` + icons + `<meta name="robots" content="noindex"></head><body></body></html>`,
+		"analyzers": []string{"seo"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.SEO == nil || response.SEO.Robots.Indexable || !reflect.DeepEqual(findingTypes(response.SEO), []string{seoFindingNoIndex}) {
+		t.Errorf("expected a noindex finding, got %+v", response.SEO)
+	}
+}
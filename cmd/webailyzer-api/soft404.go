@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// soft404MaxWords is the visible word count above which a page is treated as
+// real content even if it mentions a not-found indicator
+const soft404MaxWords = 250
+
+// soft404Patterns holds the case-insensitive not-found indicators, extended at
+// startup from SOFT_404_PATTERNS_FILE
+var soft404Patterns = defaultSoft404Patterns()
+
+// defaultSoft404Patterns returns the built-in not-found indicators
+func defaultSoft404Patterns() []string {
+	return []string{
+		"page not found",
+		"404 not found",
+		"error 404",
+		"404 error",
+		"page could not be found",
+		"page cannot be found",
+		"page can't be found",
+		"page does not exist",
+		"page doesn't exist",
+		"page you requested",
+		"page you are looking for",
+		"page you were looking for",
+		"no longer available",
+	}
+}
+
+// loadSoft404Patterns reads a JSON array of additional indicators and appends
+// them to the built-in list
+func loadSoft404Patterns(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading soft 404 patterns: %w", err)
+	}
+
+	var custom []string
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parsing soft 404 patterns: %w", err)
+	}
+
+	patterns := defaultSoft404Patterns()
+	for _, pattern := range custom {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("soft 404 patterns must not be empty")
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// hiddenElements hold content that is never shown as page text
+var hiddenElements = map[string]bool{"script": true, "style": true, "noscript": true, "template": true}
+
+// detectSoft404 reports whether a successful response looks like a "not found"
+// page, returning the matching indicator. Only thin pages qualify, so articles
+// that merely mention a missing page are not flagged.
+func detectSoft404(statusCode int, body []byte, patterns []string) (bool, string) {
+	if statusCode < 200 || statusCode >= 300 {
+		return false, ""
+	}
+
+	title, text := pageText(body)
+	if len(strings.Fields(text)) > soft404MaxWords {
+		return false, ""
+	}
+
+	haystack := strings.ToLower(title + " " + text)
+	for _, pattern := range patterns {
+		if strings.Contains(haystack, strings.ToLower(pattern)) {
+			return true, pattern
+		}
+	}
+	return false, ""
+}
+
+// pageText tokenizes the page and returns the first title and the visible text,
+// with entities decoded and whitespace collapsed. Content of script, style,
+// noscript and template elements and of comments is skipped, including an
+// unclosed script that runs to the end of the page.
+func pageText(body []byte) (title, text string) {
+	var (
+		words   []string
+		hidden  int // depth of open hidden elements
+		inTitle bool
+		seen    bool // a title has been read
+	)
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF at the end of the body; the tokenizer recovers from malformed markup itself
+			return title, strings.Join(words, " ")
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// The tokenizer reads <script/> like <script>, up to its end tag
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case hiddenElements[tag]:
+				hidden++
+			case tag == "title" && hidden == 0 && !seen:
+				inTitle = true
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case hiddenElements[tag] && hidden > 0:
+				hidden--
+			case tag == "title" && inTitle:
+				inTitle, seen = false, true
+			}
+		case html.TextToken:
+			if hidden > 0 {
+				continue
+			}
+			fields := strings.Fields(string(z.Text()))
+			if inTitle {
+				title = strings.TrimSpace(title + " " + strings.Join(fields, " "))
+			}
+			words = append(words, fields...)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const soft404TestHTML = `<html><head><title>Oops! Page Not Found</title>
+<script>var words = "lots of words that are not visible to readers";</script></head>
+<body><h1>Sorry</h1><p>The page you are looking for doesn&#39;t exist.</p><a href="/">Home</a></body></html>`
+
+func TestDetectSoft404(t *testing.T) {
+	article := "<html><head><title>How we handle page not found errors</title></head><body><p>" +
+		strings.Repeat("Our servers return proper status codes for every request. ", 60) + "</p></body></html>"
+
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   bool
+	}{
+		{"soft 404 page", http.StatusOK, soft404TestHTML, true},
+		{"normal page", http.StatusOK, `<html><head><title>Welcome</title></head><body><p>Products and pricing</p></body></html>`, false},
+		{"long article mentioning not found", http.StatusOK, article, false},
+		{"real 404", http.StatusNotFound, soft404TestHTML, false},
+		{"indicator only in a script", http.StatusOK, `<html><body><script>alert("page not found")</script><p>Hello</p></body></html>`, false},
+		{"angle bracket in an attribute", http.StatusOK, `<html><body><div data-rule="a > b">Page not found</div></body></html>`, true},
+		{"indicator in an attribute", http.StatusOK, `<html><body><img alt="page not found" src="/404.png"><p>Hello</p></body></html>`, false},
+		{"unclosed script", http.StatusOK, `<html><body><p>Hello</p><script>var msg = "page not found";`, false},
+		{"self-closed script", http.StatusOK, `<html><body><script/>var msg = "page not found";</script><p>Hello</p></body></html>`, false},
+		{"title in a comment", http.StatusOK, `<html><head><!-- <title>Page not found</title> --><title>Welcome</title></head><body>Hello</body></html>`, false},
+		{"title in a script", http.StatusOK, `<html><head><script>document.write("<title>Page not found</title>")</script></head><body>Hello</body></html>`, false},
+		{"indicator in a template", http.StatusOK, `<html><body><template><p>Page not found</p></template><p>Hello</p></body></html>`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detected, indicator := detectSoft404(tt.statusCode, []byte(tt.body), defaultSoft404Patterns())
+			if detected != tt.expected {
+				t.Errorf("expected soft 404 %v, got %v (indicator %q)", tt.expected, detected, indicator)
+			}
+			if detected && indicator == "" {
+				t.Error("expected the matching indicator to be reported")
+			}
+		})
+	}
+}
+
+func TestPageText(t *testing.T) {
+	body := `<html><head><title>Caf&eacute;
+  Menu</title><style>p > a { color: red }</style></head>
+<body><p class="x > y">Fresh&nbsp;coffee</p><!-- hidden --><template><p>Not shown</p></template>
+<noscript><p>Enable JavaScript</p></noscript><p>Open daily</p></body></html>`
+
+	title, text := pageText([]byte(body))
+	if title != "Café Menu" {
+		t.Errorf("expected title %q, got %q", "Café Menu", title)
+	}
+	if expected := "Café Menu Fresh coffee Open daily"; text != expected {
+		t.Errorf("expected text %q, got %q", expected, text)
+	}
+}
+
+func TestLoadSoft404Patterns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "soft404.json")
+	if err := os.WriteFile(path, []byte(`["Seite nicht gefunden"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns, err := loadSoft404Patterns(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := []byte(`<html><head><title>Fehler</title></head><body><p>Seite nicht gefunden</p></body></html>`)
+	if detected, indicator := detectSoft404(http.StatusOK, body, patterns); !detected || indicator != "Seite nicht gefunden" {
+		t.Errorf("expected the custom indicator to match, got %v %q", detected, indicator)
+	}
+
+	if err := os.WriteFile(path, []byte(`[""]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSoft404Patterns(path); err == nil {
+		t.Error("expected an error for an empty pattern")
+	}
+}
+
+func TestAnalyzeHandlerFlagsSoft404(t *testing.T) {
+	rr := postAnalyze(t, map[string]string{"html": soft404TestHTML})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response AnalyzeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !response.Soft404 || response.Soft404Indicator != "page not found" {
+		t.Errorf("expected a soft 404 matching %q, got %v %q", "page not found", response.Soft404, response.Soft404Indicator)
+	}
+}
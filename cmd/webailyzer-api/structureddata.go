@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// schemaValidators check the properties search engines need for rich results, by
+// schema.org type. Items of other types are reported without checks.
+var schemaValidators = map[string]func(item map[string]interface{}) []string{
+	"Article":        validateArticle,
+	"NewsArticle":    validateArticle,
+	"BlogPosting":    validateArticle,
+	"Product":        validateProduct,
+	"Organization":   validateOrganization,
+	"BreadcrumbList": validateBreadcrumbList,
+}
+
+// validateJSONLD checks each item in a JSON-LD block. A block may hold one item, an
+// array of items, or a @graph of items.
+func validateJSONLD(block string) []StructuredDataItem {
+	var data interface{}
+	if err := json.Unmarshal([]byte(block), &data); err != nil {
+		return []StructuredDataItem{{Issues: []string{fmt.Sprintf("invalid JSON: %v", err)}}}
+	}
+
+	var objects []interface{}
+	switch data := data.(type) {
+	case []interface{}:
+		objects = data
+	case map[string]interface{}:
+		if graph, ok := data["@graph"].([]interface{}); ok {
+			objects = graph
+		} else {
+			objects = []interface{}{data}
+		}
+	}
+
+	items := []StructuredDataItem{}
+	for _, object := range objects {
+		item, ok := object.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		types := schemaTypes(item["@type"])
+		result := StructuredDataItem{Type: strings.Join(types, ", "), Issues: []string{}}
+		if len(types) == 0 {
+			result.Issues = append(result.Issues, "missing @type")
+		}
+		for _, t := range types {
+			if validate, ok := schemaValidators[t]; ok {
+				result.Issues = append(result.Issues, validate(item)...)
+			}
+		}
+		result.Valid = len(result.Issues) == 0
+		if result.Valid {
+			result.Issues = nil
+		}
+		items = append(items, result)
+	}
+	return items
+}
+
+// schemaTypes reads @type, which may be one type or a list, dropping any schema.org
+// prefix such as https://schema.org/
+func schemaTypes(value interface{}) []string {
+	var raw []interface{}
+	switch value := value.(type) {
+	case string:
+		raw = []interface{}{value}
+	case []interface{}:
+		raw = value
+	}
+
+	var types []string
+	for _, v := range raw {
+		if t, ok := v.(string); ok && strings.TrimSpace(t) != "" {
+			t = t[strings.LastIndexAny(t, "/:")+1:]
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// schemaName names an item's type in messages
+func schemaName(t string) string {
+	if t == "" {
+		return "JSON-LD"
+	}
+	return t
+}
+
+func validateArticle(item map[string]interface{}) []string {
+	return missingProperties(item, "headline", "author", "datePublished")
+}
+
+func validateProduct(item map[string]interface{}) []string {
+	issues := missingProperties(item, "name", "offers")
+	if isEmptyValue(item["offers"]) {
+		return issues
+	}
+
+	// offers is one Offer, a list of them, or an AggregateOffer with lowPrice
+	offers, ok := item["offers"].([]interface{})
+	if !ok {
+		offers = []interface{}{item["offers"]}
+	}
+	for i, o := range offers {
+		offer, _ := o.(map[string]interface{})
+		if isEmptyValue(offer["price"]) && isEmptyValue(offer["lowPrice"]) && isEmptyValue(offer["priceSpecification"]) {
+			if len(offers) == 1 {
+				issues = append(issues, "offers missing price")
+			} else {
+				issues = append(issues, fmt.Sprintf("offers %d missing price", i+1))
+			}
+		}
+	}
+	return issues
+}
+
+func validateOrganization(item map[string]interface{}) []string {
+	return missingProperties(item, "name", "url")
+}
+
+func validateBreadcrumbList(item map[string]interface{}) []string {
+	elements, _ := item["itemListElement"].([]interface{})
+	if len(elements) == 0 {
+		return []string{"missing itemListElement"}
+	}
+
+	var issues []string
+	for i, e := range elements {
+		element, _ := e.(map[string]interface{})
+		if isEmptyValue(element["position"]) {
+			issues = append(issues, fmt.Sprintf("itemListElement %d missing position", i+1))
+		}
+		// The name may be given on the ListItem or on the item it links to
+		named := !isEmptyValue(element["name"])
+		if linked, ok := element["item"].(map[string]interface{}); ok && !isEmptyValue(linked["name"]) {
+			named = true
+		}
+		if !named {
+			issues = append(issues, fmt.Sprintf("itemListElement %d missing name", i+1))
+		}
+	}
+	return issues
+}
+
+// missingProperties lists the properties that are absent or empty
+func missingProperties(item map[string]interface{}, properties ...string) []string {
+	var issues []string
+	for _, property := range properties {
+		if isEmptyValue(item[property]) {
+			issues = append(issues, "missing "+property)
+		}
+	}
+	return issues
+}
+
+// isEmptyValue reports whether a JSON value is absent, blank or an empty list or object
+func isEmptyValue(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(value) == ""
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
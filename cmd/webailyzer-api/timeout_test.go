@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -11,8 +12,9 @@ func TestHTTPClientTimeout(t *testing.T) {
 	// Test that our HTTP client has proper timeout configuration
 	client := createHTTPClient()
 	
-	if client.Timeout != 15*time.Second {
-		t.Errorf("Expected client timeout of 15s, got %v", client.Timeout)
+	// Fetches are bounded by the analysis budget, not fixed client timeouts
+	if client.Timeout != 0 {
+		t.Errorf("Expected no overall client timeout, got %v", client.Timeout)
 	}
 	
 	transport, ok := client.Transport.(*http.Transport)
@@ -20,8 +22,8 @@ func TestHTTPClientTimeout(t *testing.T) {
 		t.Fatal("Expected *http.Transport")
 	}
 	
-	if transport.ResponseHeaderTimeout != 10*time.Second {
-		t.Errorf("Expected ResponseHeaderTimeout of 10s, got %v", transport.ResponseHeaderTimeout)
+	if transport.ResponseHeaderTimeout != 0 {
+		t.Errorf("Expected no fixed ResponseHeaderTimeout, got %v", transport.ResponseHeaderTimeout)
 	}
 	
 	if transport.TLSHandshakeTimeout != 10*time.Second {
@@ -71,4 +73,31 @@ func TestRedirectLimit(t *testing.T) {
 	if redirectCount != 10 {
 		t.Errorf("Expected redirect limit of 10, got %d", redirectCount)
 	}
-}
\ No newline at end of file
+}
+func TestTimeoutMiddlewareGivesAnalysisRoutesTheAnalysisBudget(t *testing.T) {
+	saved := defaultRequestTimeout
+	defaultRequestTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { defaultRequestTimeout = saved })
+
+	tests := map[string]bool{
+		"/v1/analyze":                  true,
+		"/v1/analyze/compare":          true,
+		"/v1/analyses/abc/reanalyze":   true,
+		"/v1/categories":               false,
+		"/v1/jobs":                     false,
+		"/v1/analyses/abc/other-thing": false,
+	}
+
+	for path, long := range tests {
+		var remaining time.Duration
+		handler := timeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, _ := r.Context().Deadline()
+			remaining = time.Until(deadline)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", path, nil))
+
+		if got := remaining > defaultRequestTimeout; got != long {
+			t.Errorf("%s: expected the analysis budget %v, got %v remaining", path, long, remaining)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/webailyzer/webailyzer-lite-api/internal/buildinfo"
+)
+
+// VersionResponse reports which build is running and the size of its fingerprint database
+type VersionResponse struct {
+	Version          string `json:"version"`
+	Commit           string `json:"commit"`
+	BuildTime        string `json:"build_time"`
+	FingerprintCount int    `json:"fingerprint_count"`
+}
+
+// versionHandler handles GET /version requests
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := ""
+	if id := r.Context().Value("request_id"); id != nil {
+		requestID = id.(string)
+	}
+
+	wc, err := getWappalyzer()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Wappalyzer initialization failed")
+
+		sendErrorResponse(w, APIError{
+			Type:       ErrorTypeInternal,
+			Message:    "Technology detection engine failed",
+			Details:    "Unable to initialize the technology detection engine",
+			StatusCode: http.StatusInternalServerError,
+			RequestID:  requestID,
+		})
+		return
+	}
+
+	response := VersionResponse{
+		Version:          buildinfo.Version,
+		Commit:           buildinfo.Commit,
+		BuildTime:        buildinfo.BuildTime,
+		FingerprintCount: len(wc.GetFingerprints().Apps),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Failed to encode version response")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/webailyzer/webailyzer-lite-api/internal/buildinfo"
+)
+
+func TestVersionHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/version", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	versionHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &fields); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	for _, name := range []string{"version", "commit", "build_time", "fingerprint_count"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("expected field %s in %v", name, fields)
+		}
+	}
+
+	var response VersionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Version != buildinfo.Version || response.Commit != buildinfo.Commit || response.BuildTime != buildinfo.BuildTime {
+		t.Errorf("expected build metadata from buildinfo, got %+v", response)
+	}
+	if response.FingerprintCount <= 0 {
+		t.Errorf("expected a positive fingerprint count, got %d", response.FingerprintCount)
+	}
+}
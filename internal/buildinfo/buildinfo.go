@@ -0,0 +1,15 @@
+// Package buildinfo holds build metadata shared by the commands in this module.
+//
+// The values are set at link time, for example:
+//
+//	go build -ldflags "-X github.com/webailyzer/webailyzer-lite-api/internal/buildinfo.Version=1.2.0 \
+//	  -X github.com/webailyzer/webailyzer-lite-api/internal/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/webailyzer/webailyzer-lite-api/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+// Build metadata, overridden with -ldflags "-X ..."; the defaults identify a local development build
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)